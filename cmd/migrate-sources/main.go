@@ -0,0 +1,58 @@
+// Command migrate-sources moves the sources of solutions kept in the database
+// to the object storage. The server reads both storages, so it may keep running.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/Vyacheslav1557/tester/config"
+	solutionsRepository "github.com/Vyacheslav1557/tester/internal/solutions/repository"
+	solutionsUseCase "github.com/Vyacheslav1557/tester/internal/solutions/usecase"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"github.com/ilyakaznacheev/cleanenv"
+	"go.uber.org/zap"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	batch := flag.Int("batch", 100, "number of sources read from the database at once")
+	flag.Parse()
+
+	var cfg config.Config
+	err := cleanenv.ReadConfig(".env", &cfg)
+	if err != nil {
+		panic(fmt.Sprintf("error reading config: %s", err.Error()))
+	}
+
+	logger := zap.Must(zap.NewProduction())
+	defer logger.Sync()
+
+	db, err := pkg.NewPostgresDB(cfg.PostgresDSN)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("error connecting to postgres: %v", err))
+	}
+	defer db.Close()
+
+	s3Client, err := pkg.NewS3Client(cfg.S3Endpoint, cfg.S3AccessKey, cfg.S3SecretKey)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("error connecting to s3: %v", err))
+	}
+
+	// the sources moved so far are kept if the migration is interrupted
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	moved, err := solutionsUseCase.MigrateSources(ctx,
+		solutionsRepository.NewRepository(db),
+		solutionsRepository.NewS3Repository(s3Client, "tester-solutions-archives"),
+		int32(*batch),
+	)
+	logger.Info(fmt.Sprintf("moved %d sources to the object storage", moved))
+	if err != nil {
+		logger.Error(fmt.Sprintf("error moving sources: %v", err))
+		os.Exit(1)
+	}
+}
@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 type Config struct {
 	Env string `env:"ENV" env-default:"prod"`
 
@@ -20,8 +22,26 @@ type Config struct {
 
 	CacheDir string `env:"CACHE_DIR" env-default:"/tmp"`
 
+	// LanguagesFile is a YAML language registry, the built-in one is used if empty
+	LanguagesFile string `env:"LANGUAGES_FILE"`
+
+	// Executor runs solutions, either "docker" or "native" (linux namespaces and cgroups v2)
+	Executor string `env:"EXECUTOR" env-default:"docker"`
+	// SandboxRoot holds a rootfs per language id for the native executor
+	SandboxRoot string `env:"SANDBOX_ROOT" env-default:"/var/lib/tester/rootfs"`
+	// SandboxCgroup is the cgroup v2 directory delegated to the native executor
+	SandboxCgroup string `env:"SANDBOX_CGROUP" env-default:"/sys/fs/cgroup/tester"`
+
 	NatsUrl string `env:"NATS_URL" env-default:"nats://localhost:4222"`
 
+	// JudgeRetries is how many times a solution is retried after the judge has failed on it
+	JudgeRetries int `env:"JUDGE_RETRIES" env-default:"3"`
+
+	// SubmitLimit solutions per SubmitWindow are allowed to a student in a contest,
+	// contests may override both
+	SubmitLimit  int32         `env:"SUBMIT_LIMIT" env-default:"10"`
+	SubmitWindow time.Duration `env:"SUBMIT_WINDOW" env-default:"1m"`
+
 	//RabbitDSN    string `env:"RABBIT_DSN" required:"true"`
 	//InstanceName string `env:"INSTANCE_NAME" required:"true"`
 	//RQueueName   string `env:"R_QUEUE_NAME" required:"true"`
@@ -12,6 +12,7 @@ type ContestsHandlers interface {
 
 	CreateContestProblem(c *fiber.Ctx, contestId int32, params testerv1.CreateContestProblemParams) error
 	GetContestProblem(c *fiber.Ctx, contestId int32, problemId int32) error
+	UpdateContestProblem(c *fiber.Ctx, contestId int32, problemId int32) error
 	DeleteContestProblem(c *fiber.Ctx, contestId int32, problemId int32) error
 
 	CreateContest(c *fiber.Ctx, params testerv1.CreateContestParams) error
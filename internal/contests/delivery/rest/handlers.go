@@ -128,7 +128,13 @@ func (h *Handlers) UpdateContest(c *fiber.Ctx, id int32) error {
 		}
 
 		err = h.contestsUC.UpdateContest(ctx, id, models.ContestUpdate{
-			Title: req.Title,
+			Title:     req.Title,
+			Languages: languagesP(req.Languages),
+			Strategy:  strategyP(req.Strategy),
+			Kind:      kindP(req.Kind),
+
+			SubmitLimit:  req.SubmitLimit,
+			SubmitWindow: req.SubmitWindow,
 		})
 		if err != nil {
 			return err
@@ -253,6 +259,37 @@ func (h *Handlers) GetContestProblem(c *fiber.Ctx, contestId int32, problemId in
 	}
 }
 
+func (h *Handlers) UpdateContestProblem(c *fiber.Ctx, contestId int32, problemId int32) error {
+	const op = "ContestsHandlers.UpdateContestProblem"
+
+	ctx := c.Context()
+
+	session, err := sessionFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch session.Role {
+	case models.RoleAdmin, models.RoleTeacher:
+		var req testerv1.UpdateContestProblemRequest
+		err := c.BodyParser(&req)
+		if err != nil {
+			return pkg.Wrap(pkg.ErrBadInput, err, op, "failed to parse request")
+		}
+
+		err = h.contestsUC.UpdateContestProblem(ctx, contestId, problemId, models.ContestProblemUpdate{
+			Languages: languagesP(req.Languages),
+		})
+		if err != nil {
+			return err
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	default:
+		return pkg.NoPermission
+	}
+}
+
 func (h *Handlers) DeleteContestProblem(c *fiber.Ctx, contestId int32, problemId int32) error {
 	ctx := c.Context()
 
@@ -413,7 +450,10 @@ func GetContestProblemResponseDTO(p *models.ContestProblem) *testerv1.GetContest
 			ScoringHtml:      p.ScoringHtml,
 
 			//Meta:             MetaDTO(p.Meta),
-			//Samples:          SamplesDTO(p.Samples),
+			Samples:   SamplesDTO(p.Samples),
+			Languages: LanguagesDTO(p.Languages),
+			Strategy:  int32(p.Strategy),
+			Templates: TemplatesDTO(p.Graders, p.Languages),
 
 			CreatedAt: p.CreatedAt,
 			UpdatedAt: p.UpdatedAt,
@@ -423,6 +463,71 @@ func GetContestProblemResponseDTO(p *models.ContestProblem) *testerv1.GetContest
 	return &resp
 }
 
+// TemplatesDTO returns the starter code of the allowed languages,
+// the graders themselves are never shown to the participants
+func TemplatesDTO(g models.Graders, allowed models.Languages) []testerv1.ProblemTemplate {
+	templates := make([]testerv1.ProblemTemplate, 0, len(g))
+	for _, grader := range g {
+		if !allowed.Contains(grader.Language) {
+			continue
+		}
+		templates = append(templates, testerv1.ProblemTemplate{
+			Language: int32(grader.Language),
+			Template: grader.Template,
+		})
+	}
+	return templates
+}
+
+func SamplesDTO(s models.Samples) []testerv1.Sample {
+	samples := make([]testerv1.Sample, len(s))
+	for i, sample := range s {
+		samples[i] = testerv1.Sample{
+			Input:  sample.Input,
+			Output: sample.Output,
+		}
+	}
+	return samples
+}
+
+func LanguagesDTO(l models.Languages) []int32 {
+	languages := make([]int32, len(l))
+	for i, lang := range l {
+		languages[i] = int32(lang)
+	}
+	return languages
+}
+
+func languagesP(l *[]int32) *models.Languages {
+	if l == nil {
+		return nil
+	}
+
+	languages := make(models.Languages, len(*l))
+	for i, lang := range *l {
+		languages[i] = models.LanguageName(lang)
+	}
+	return &languages
+}
+
+func strategyP(s *int32) *models.Strategy {
+	if s == nil {
+		return nil
+	}
+
+	strategy := models.Strategy(*s)
+	return &strategy
+}
+
+func kindP(k *int32) *models.ContestKind {
+	if k == nil {
+		return nil
+	}
+
+	kind := models.ContestKind(*k)
+	return &kind
+}
+
 func PaginationDTO(p models.Pagination) testerv1.Pagination {
 	return testerv1.Pagination{
 		Page:  p.Page,
@@ -434,6 +539,13 @@ func ContestDTO(c models.Contest) testerv1.Contest {
 	return testerv1.Contest{
 		Id:        c.Id,
 		Title:     c.Title,
+		Languages: LanguagesDTO(c.Languages),
+		Strategy:  int32(c.Strategy),
+		Kind:      int32(c.Kind),
+
+		SubmitLimit:  c.SubmitLimit,
+		SubmitWindow: c.SubmitWindow,
+
 		CreatedAt: c.CreatedAt,
 		UpdatedAt: c.UpdatedAt,
 	}
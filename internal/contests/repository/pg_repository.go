@@ -56,13 +56,22 @@ func (r *Repository) GetContest(ctx context.Context, id int32) (*models.Contest,
 }
 
 const (
-	UpdateContestQuery = "UPDATE contests SET title = COALESCE($1, title) WHERE id = $2"
+	UpdateContestQuery = `
+UPDATE contests
+SET title         = COALESCE($1, title),
+    languages     = COALESCE($3, languages),
+    strategy      = COALESCE($4, strategy),
+    kind          = COALESCE($5, kind),
+    submit_limit  = COALESCE($6, submit_limit),
+    submit_window = COALESCE($7, submit_window)
+WHERE id = $2`
 )
 
 func (r *Repository) UpdateContest(ctx context.Context, id int32, contestUpdate models.ContestUpdate) error {
 	const op = "Repository.UpdateContest"
 
-	_, err := r.db.ExecContext(ctx, UpdateContestQuery, contestUpdate.Title, id)
+	_, err := r.db.ExecContext(ctx, UpdateContestQuery, contestUpdate.Title, id, contestUpdate.Languages, contestUpdate.Strategy, contestUpdate.Kind,
+		contestUpdate.SubmitLimit, contestUpdate.SubmitWindow)
 	if err != nil {
 		return pkg.HandlePgErr(err, op)
 	}
@@ -188,9 +197,19 @@ SELECT cp.problem_id,
 	   p.scoring_html,
 	   p.meta,
 	   p.samples,
+	   p.graders,
+	   c.languages  AS contest_languages,
+	   cp.languages AS problem_languages,
+	   c.strategy   AS contest_strategy,
+	   p.strategy   AS problem_strategy,
+	   c.kind       AS contest_kind,
+	   c.submit_limit  AS contest_submit_limit,
+	   c.submit_window AS contest_submit_window,
 	   p.created_at,
 	   p.updated_at
-FROM contest_problem cp LEFT JOIN problems p ON cp.problem_id = p.id
+FROM contest_problem cp
+         LEFT JOIN problems p ON cp.problem_id = p.id
+         LEFT JOIN contests c ON cp.contest_id = c.id
 WHERE cp.contest_id = $1 AND cp.problem_id = $2
 `
 
@@ -206,6 +225,30 @@ func (r *Repository) GetContestProblem(ctx context.Context, contestId, problemId
 	return &contestProblem, nil
 }
 
+const UpdateContestProblemQuery = `UPDATE contest_problem
+SET languages = COALESCE($3, languages)
+WHERE contest_id = $1 AND problem_id = $2`
+
+func (r *Repository) UpdateContestProblem(ctx context.Context, contestId, problemId int32, update models.ContestProblemUpdate) error {
+	const op = "Repository.UpdateContestProblem"
+
+	res, err := r.db.ExecContext(ctx, UpdateContestProblemQuery, contestId, problemId, update.Languages)
+	if err != nil {
+		return pkg.HandlePgErr(err, op)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return pkg.HandlePgErr(err, op)
+	}
+
+	if affected == 0 {
+		return pkg.Wrap(pkg.ErrNotFound, nil, op, "contest problem not found")
+	}
+
+	return nil
+}
+
 const GetContestProblemsQuery = `
 SELECT cp.problem_id,
 	   p.title,
@@ -317,7 +360,7 @@ const GetMonitorParticipantsQuery = `
 SELECT cu.user_id, u.username, COUNT(DISTINCT s.problem_id) as solved_problems, 0 as penalty
 FROM contest_user cu
          LEFT JOIN solutions s ON cu.user_id = s.user_id
-    AND cu.contest_id = s.contest_id AND s.state = 200
+    AND cu.contest_id = s.contest_id AND s.state = 200 AND NOT s.pretest
          LEFT JOIN users u ON cu.user_id = u.id
 WHERE cu.contest_id = $1
 GROUP BY (cu.user_id, u.username)
@@ -326,13 +369,14 @@ GROUP BY (cu.user_id, u.username)
 const GetMonitorStatistics = `
 SELECT cp.problem_id,
        COUNT(CASE WHEN s.state = 200 THEN 1 END)                   AS s_atts,
-       COUNT(CASE WHEN s.state != 200 AND s.state != 1 THEN 1 END) AS uns_atts,
+       COUNT(CASE WHEN s.state BETWEEN 101 AND 199 THEN 1 END)     AS uns_atts,
        COUNT(*)                                                    AS t_atts,
        cp.position
 FROM contest_problem cp
          LEFT JOIN
      solutions s ON cp.problem_id = s.problem_id
          AND cp.contest_id = s.contest_id
+         AND NOT s.pretest
 WHERE cp.contest_id = $1
 GROUP BY (cp.problem_id, cp.position)
 ORDER BY cp.problem_id
@@ -363,6 +407,7 @@ WITH UserSolutions AS (
             LEFT JOIN solutions s ON cu.user_id = s.user_id
             AND cp.problem_id = s.problem_id
             AND cu.contest_id = s.contest_id
+            AND NOT s.pretest
     WHERE
             cu.contest_id = $1
 ),
@@ -372,8 +417,7 @@ FailedAttempts AS (
         problem_id,
         position,
         COUNT(
-            CASE WHEN state != 200
-                AND state != 1
+            CASE WHEN state BETWEEN 101 AND 199
                 AND (
                 first_success_time IS NULL
                 OR created_at < first_success_time
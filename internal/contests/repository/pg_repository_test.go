@@ -5,6 +5,7 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/Vyacheslav1557/tester/internal/contests/repository"
 	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/Vyacheslav1557/tester/pkg"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -80,12 +81,20 @@ func TestRepository_UpdateContest(t *testing.T) {
 		ctx := context.Background()
 
 		var contestId int32 = 1
+		strategy := models.StrategyIOI
+		kind := models.ContestLive
 		update := models.ContestUpdate{
-			Title: sp("Updated Contest"),
+			Title:     sp("Updated Contest"),
+			Languages: &models.Languages{models.Python},
+			Strategy:  &strategy,
+			Kind:      &kind,
+
+			SubmitLimit:  ip(5),
+			SubmitWindow: ip(60),
 		}
 
 		mock.ExpectExec(repository.UpdateContestQuery).
-			WithArgs(update.Title, contestId).
+			WithArgs(update.Title, contestId, []byte("[30]"), int64(models.StrategyIOI), int64(models.ContestLive), int64(5), int64(60)).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 
 		err := repo.UpdateContest(ctx, contestId, update)
@@ -93,6 +102,40 @@ func TestRepository_UpdateContest(t *testing.T) {
 	})
 }
 
+func TestRepository_UpdateContestProblem(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewRepository(db)
+
+	var contestId, problemId int32 = 1, 2
+	update := models.ContestProblemUpdate{
+		Languages: &models.Languages{models.Cpp, models.Golang},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		ctx := context.Background()
+
+		mock.ExpectExec(repository.UpdateContestProblemQuery).
+			WithArgs(contestId, problemId, []byte("[20,10]")).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.UpdateContestProblem(ctx, contestId, problemId, update)
+		assert.NoError(t, err)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		ctx := context.Background()
+
+		mock.ExpectExec(repository.UpdateContestProblemQuery).
+			WithArgs(contestId, problemId, []byte("[20,10]")).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.UpdateContestProblem(ctx, contestId, problemId, update)
+		assert.ErrorIs(t, err, pkg.ErrNotFound)
+	})
+}
+
 func TestRepository_DeleteContest(t *testing.T) {
 	db, mock := setupTestDB(t)
 	defer db.Close()
@@ -114,3 +157,7 @@ func TestRepository_DeleteContest(t *testing.T) {
 func sp(s string) *string {
 	return &s
 }
+
+func ip(i int32) *int32 {
+	return &i
+}
@@ -14,6 +14,7 @@ type UseCase interface {
 
 	CreateContestProblem(ctx context.Context, contestId, problemId int32) error
 	GetContestProblem(ctx context.Context, contestId int32, problemId int32) (*models.ContestProblem, error)
+	UpdateContestProblem(ctx context.Context, contestId, problemId int32, update models.ContestProblemUpdate) error
 	GetContestProblems(ctx context.Context, contestId int32) ([]*models.ContestProblemsListItem, error)
 	DeleteContestProblem(ctx context.Context, contestId, problemId int32) error
 
@@ -4,17 +4,22 @@ import (
 	"context"
 	"github.com/Vyacheslav1557/tester/internal/contests"
 	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/Vyacheslav1557/tester/internal/problems"
+	"github.com/Vyacheslav1557/tester/pkg"
 )
 
 type UseCase struct {
 	contestRepo contests.Repository
+	problemsUC  problems.UseCase
 }
 
 func NewContestUseCase(
 	contestRepo contests.Repository,
+	problemsUC problems.UseCase,
 ) *UseCase {
 	return &UseCase{
 		contestRepo: contestRepo,
+		problemsUC:  problemsUC,
 	}
 }
 
@@ -31,6 +36,34 @@ func (uc *UseCase) ListContests(ctx context.Context, filter models.ContestsFilte
 }
 
 func (uc *UseCase) UpdateContest(ctx context.Context, id int32, contestUpdate models.ContestUpdate) error {
+	const op = "UseCase.UpdateContest"
+
+	if contestUpdate.Languages != nil {
+		if err := contestUpdate.Languages.Valid(); err != nil {
+			return err
+		}
+	}
+
+	if contestUpdate.Strategy != nil {
+		if err := contestUpdate.Strategy.Valid(); err != nil {
+			return err
+		}
+	}
+
+	if contestUpdate.Kind != nil {
+		if err := contestUpdate.Kind.Valid(); err != nil {
+			return err
+		}
+	}
+
+	if contestUpdate.SubmitLimit != nil && *contestUpdate.SubmitLimit < models.SubmitLimitUnlimited {
+		return pkg.Wrap(pkg.ErrBadInput, nil, op, "invalid submit limit")
+	}
+
+	if contestUpdate.SubmitWindow != nil && *contestUpdate.SubmitWindow < 0 {
+		return pkg.Wrap(pkg.ErrBadInput, nil, op, "invalid submit window")
+	}
+
 	return uc.contestRepo.UpdateContest(ctx, id, contestUpdate)
 }
 
@@ -39,11 +72,48 @@ func (uc *UseCase) DeleteContest(ctx context.Context, id int32) error {
 }
 
 func (uc *UseCase) CreateContestProblem(ctx context.Context, contestId, problemId int32) error {
+	const op = "UseCase.CreateContestProblem"
+
+	problem, err := uc.problemsUC.GetProblemById(ctx, problemId)
+	if err != nil {
+		return err
+	}
+
+	if problem.JuryReport.MainFailed() {
+		return pkg.Wrap(pkg.ErrBadInput, nil, op, "main jury solution of the problem fails")
+	}
+
 	return uc.contestRepo.CreateContestProblem(ctx, contestId, problemId)
 }
 
 func (uc *UseCase) GetContestProblem(ctx context.Context, contestId, problemId int32) (*models.ContestProblem, error) {
-	return uc.contestRepo.GetContestProblem(ctx, contestId, problemId)
+	problem, err := uc.contestRepo.GetContestProblem(ctx, contestId, problemId)
+	if err != nil {
+		return nil, err
+	}
+
+	problem.Languages = models.EffectiveLanguages(problem.ContestLanguages, problem.ProblemLanguages)
+	if len(problem.Graders) != 0 {
+		// a function is submitted, so only the languages with a grader fit
+		problem.Languages = models.EffectiveLanguages(problem.Languages, problem.Graders.Languages())
+	}
+	problem.Strategy = models.EffectiveStrategy(problem.ContestStrategy, problem.ProblemStrategy)
+
+	return problem, nil
+}
+
+func (uc *UseCase) UpdateContestProblem(ctx context.Context, contestId, problemId int32, update models.ContestProblemUpdate) error {
+	const op = "UseCase.UpdateContestProblem"
+
+	if update.Languages == nil {
+		return pkg.Wrap(pkg.ErrBadInput, nil, op, "empty contest problem update")
+	}
+
+	if err := update.Languages.Valid(); err != nil {
+		return err
+	}
+
+	return uc.contestRepo.UpdateContestProblem(ctx, contestId, problemId, update)
 }
 
 func (uc *UseCase) GetContestProblems(ctx context.Context, contestId int32) ([]*models.ContestProblemsListItem, error) {
@@ -0,0 +1,9 @@
+package languages
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+type LanguagesHandlers interface {
+	ListLanguages(c *fiber.Ctx) error
+}
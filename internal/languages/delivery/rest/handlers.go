@@ -0,0 +1,44 @@
+package rest
+
+import (
+	testerv1 "github.com/Vyacheslav1557/tester/contracts/tester/v1"
+	"github.com/Vyacheslav1557/tester/internal/languages"
+	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handlers struct {
+	languagesUC languages.UseCase
+}
+
+func NewHandlers(languagesUC languages.UseCase) *Handlers {
+	return &Handlers{
+		languagesUC: languagesUC,
+	}
+}
+
+func (h *Handlers) ListLanguages(c *fiber.Ctx) error {
+	langs, err := h.languagesUC.ListLanguages(c.Context())
+	if err != nil {
+		return err
+	}
+
+	resp := testerv1.ListLanguagesResponse{
+		Languages: make([]testerv1.Language, len(langs)),
+	}
+
+	for i, lang := range langs {
+		resp.Languages[i] = LanguageDTO(lang)
+	}
+
+	return c.JSON(resp)
+}
+
+func LanguageDTO(l *models.Language) testerv1.Language {
+	return testerv1.Language{
+		Id:               int32(l.Id),
+		Name:             l.Name,
+		TimeMultiplier:   float32(l.TimeMultiplier),
+		MemoryMultiplier: float32(l.MemoryMultiplier),
+	}
+}
@@ -0,0 +1,10 @@
+package languages
+
+import (
+	"context"
+	"github.com/Vyacheslav1557/tester/internal/models"
+)
+
+type UseCase interface {
+	ListLanguages(ctx context.Context) ([]*models.Language, error)
+}
@@ -0,0 +1,33 @@
+package usecase
+
+import (
+	"context"
+	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/Vyacheslav1557/tester/pkg/tester"
+)
+
+type UseCase struct {
+	registry *tester.Registry
+}
+
+func NewUseCase(registry *tester.Registry) *UseCase {
+	return &UseCase{
+		registry: registry,
+	}
+}
+
+func (uc *UseCase) ListLanguages(_ context.Context) ([]*models.Language, error) {
+	enabled := uc.registry.List()
+
+	languages := make([]*models.Language, len(enabled))
+	for i, lang := range enabled {
+		languages[i] = &models.Language{
+			Id:               lang.Id,
+			Name:             lang.Name,
+			TimeMultiplier:   lang.TimeMultiplier,
+			MemoryMultiplier: lang.MemoryMultiplier,
+		}
+	}
+
+	return languages, nil
+}
@@ -1,12 +1,69 @@
 package models
 
-import "time"
+import (
+	"github.com/Vyacheslav1557/tester/pkg"
+	"time"
+)
+
+// ContestKind tells how urgent the solutions of a contest are for the judge
+type ContestKind int32
+
+const (
+	ContestPractice ContestKind = 0
+	ContestHomework ContestKind = 1
+	ContestLive     ContestKind = 2
+)
+
+func (k ContestKind) Valid() error {
+	const op = "ContestKind.Valid"
+
+	switch k {
+	case ContestPractice, ContestHomework, ContestLive:
+		return nil
+	}
+
+	return pkg.Wrap(pkg.ErrBadInput, nil, op, "invalid contest kind")
+}
+
+// SubmitLimit allows a user Count solutions per Window in a contest
+type SubmitLimit struct {
+	Count  int32
+	Window time.Duration
+}
+
+const (
+	SubmitLimitDefault   int32 = 0  // of contests only, the limit of the service is used
+	SubmitLimitUnlimited int32 = -1 // of contests only, there is no limit
+)
+
+// EffectiveSubmitLimit overrides the limit of the service with the one of the contest,
+// ok is false if the contest has no limit at all
+func EffectiveSubmitLimit(service SubmitLimit, count, windowSeconds int32) (limit SubmitLimit, ok bool) {
+	if count == SubmitLimitUnlimited {
+		return SubmitLimit{}, false
+	}
+
+	limit = service
+	if count != SubmitLimitDefault {
+		limit.Count = count
+	}
+	if windowSeconds != 0 {
+		limit.Window = time.Duration(windowSeconds) * time.Second
+	}
+
+	return limit, limit.Count > 0 && limit.Window > 0
+}
 
 type Contest struct {
-	Id        int32     `db:"id"`
-	Title     string    `db:"title"`
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
+	Id           int32       `db:"id"`
+	Title        string      `db:"title"`
+	Languages    Languages   `db:"languages"` // JSONB field
+	Strategy     Strategy    `db:"strategy"`
+	Kind         ContestKind `db:"kind"`
+	SubmitLimit  int32       `db:"submit_limit"`  // solutions per user per window, see SubmitLimitDefault
+	SubmitWindow int32       `db:"submit_window"` // seconds, zero for the window of the service
+	CreatedAt    time.Time   `db:"created_at"`
+	UpdatedAt    time.Time   `db:"updated_at"`
 }
 
 type ContestsList struct {
@@ -26,7 +83,17 @@ func (f ContestsFilter) Offset() int32 {
 }
 
 type ContestUpdate struct {
-	Title *string `json:"title"`
+	Title     *string      `json:"title"`
+	Languages *Languages   `json:"languages"`
+	Strategy  *Strategy    `json:"strategy"`
+	Kind      *ContestKind `json:"kind"`
+
+	SubmitLimit  *int32 `json:"submit_limit"`
+	SubmitWindow *int32 `json:"submit_window"`
+}
+
+type ContestProblemUpdate struct {
+	Languages *Languages `json:"languages"`
 }
 
 type Monitor struct {
@@ -84,6 +151,20 @@ type ContestProblem struct {
 
 	Meta    Meta    `db:"meta"`    // JSONB field
 	Samples Samples `db:"samples"` // JSONB field
+	Graders Graders `db:"graders"` // JSONB field
+
+	ContestLanguages Languages `db:"contest_languages"` // JSONB field
+	ProblemLanguages Languages `db:"problem_languages"` // JSONB field
+	Languages        Languages `db:"-"`                 // effective, set by the use case
+
+	ContestStrategy Strategy `db:"contest_strategy"`
+	ProblemStrategy Strategy `db:"problem_strategy"`
+	Strategy        Strategy `db:"-"` // effective, set by the use case
+
+	ContestKind ContestKind `db:"contest_kind"`
+
+	ContestSubmitLimit  int32 `db:"contest_submit_limit"`
+	ContestSubmitWindow int32 `db:"contest_submit_window"`
 
 	CreatedAt time.Time `db:"created_at"`
 	UpdatedAt time.Time `db:"updated_at"`
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"github.com/Vyacheslav1557/tester/pkg"
+)
+
+// Grader is the jury code a solution is compiled with, e.g. the main reading
+// the input and calling the function the participants implement
+type Grader struct {
+	Language LanguageName `json:"language"`
+	Source   string       `json:"source"`
+	Template string       `json:"template"` // starter code shown to the participants
+}
+
+// Graders are the graders of a problem, at most one per language,
+// a problem without graders is solved by complete programs
+type Graders []Grader
+
+func (g *Graders) Scan(src interface{}) error {
+	if src == nil {
+		*g = Graders{}
+		return nil
+	}
+
+	// Expect src to be []byte (JSONB data)
+	data, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("expected []byte for JSONB, got %T", src)
+	}
+
+	// Unmarshal JSON into Graders
+	return json.Unmarshal(data, g)
+}
+
+func (g Graders) Value() (driver.Value, error) {
+	if g == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(g)
+}
+
+func (g Graders) Valid() error {
+	const op = "Graders.Valid"
+
+	seen := make(map[LanguageName]bool, len(g))
+	for _, grader := range g {
+		if err := grader.Language.Valid(); err != nil {
+			return err
+		}
+		if seen[grader.Language] {
+			return pkg.Wrap(pkg.ErrBadInput, nil, op, "duplicate grader language")
+		}
+		if grader.Source == "" {
+			return pkg.Wrap(pkg.ErrBadInput, nil, op, "empty grader source")
+		}
+		seen[grader.Language] = true
+	}
+
+	return nil
+}
+
+// Get returns the grader for the language, nil if there is none
+func (g Graders) Get(n LanguageName) *Grader {
+	for i := range g {
+		if g[i].Language == n {
+			return &g[i]
+		}
+	}
+	return nil
+}
+
+// Languages returns the languages the problem can be solved in
+func (g Graders) Languages() Languages {
+	res := make(Languages, len(g))
+	for i, grader := range g {
+		res[i] = grader.Language
+	}
+	return res
+}
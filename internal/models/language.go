@@ -0,0 +1,88 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"github.com/Vyacheslav1557/tester/pkg"
+)
+
+type Language struct {
+	Id               LanguageName
+	Name             string
+	TimeMultiplier   float64
+	MemoryMultiplier float64
+}
+
+// Languages is a list of allowed languages, an empty list means no restriction
+type Languages []LanguageName
+
+func (l *Languages) Scan(src interface{}) error {
+	if src == nil {
+		*l = Languages{}
+		return nil
+	}
+
+	// Expect src to be []byte (JSONB data)
+	data, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("expected []byte for JSONB, got %T", src)
+	}
+
+	// Unmarshal JSON into Languages
+	return json.Unmarshal(data, l)
+}
+
+func (l Languages) Value() (driver.Value, error) {
+	if l == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(l)
+}
+
+func (l Languages) Valid() error {
+	const op = "Languages.Valid"
+
+	seen := make(map[LanguageName]bool, len(l))
+	for _, n := range l {
+		if err := n.Valid(); err != nil {
+			return err
+		}
+		if seen[n] {
+			return pkg.Wrap(pkg.ErrBadInput, nil, op, "duplicate language")
+		}
+		seen[n] = true
+	}
+
+	return nil
+}
+
+func (l Languages) Contains(n LanguageName) bool {
+	for _, lang := range l {
+		if lang == n {
+			return true
+		}
+	}
+	return false
+}
+
+// EffectiveLanguages returns the enabled languages allowed by every non-empty restriction
+func EffectiveLanguages(restrictions ...Languages) Languages {
+	res := Languages{}
+
+	for _, n := range EnabledLanguages() {
+		allowed := true
+		for _, r := range restrictions {
+			if len(r) != 0 && !r.Contains(n) {
+				allowed = false
+				break
+			}
+		}
+
+		if allowed {
+			res = append(res, n)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// PlagiarismCandidate is the last accepted solution of a participant
+type PlagiarismCandidate struct {
+	Id       int32        `db:"id"`
+	UserId   int32        `db:"user_id"`
+	Language LanguageName `db:"language"`
+	Solution string       `db:"solution"`
+
+	SourceKey  *string `db:"source_key"` // nil if Solution is kept in the database
+	SourceHash string  `db:"source_hash"`
+	SourceSize int32   `db:"source_size"`
+}
+
+// PlagiarismPair is the similarity of two solutions of a contest problem, SolutionA < SolutionB
+type PlagiarismPair struct {
+	SolutionA int32  `db:"solution_a"`
+	UserA     int32  `db:"user_a"`
+	UsernameA string `db:"username_a"`
+
+	SolutionB int32  `db:"solution_b"`
+	UserB     int32  `db:"user_b"`
+	UsernameB string `db:"username_b"`
+
+	Similarity float64   `db:"similarity"` // 0 to 1
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+// PlagiarismReport is the pairs of a contest problem, the most similar first
+type PlagiarismReport struct {
+	ContestId int32
+	ProblemId int32
+	Pairs     []*PlagiarismPair
+}
+
+// SideBySideRow is a line of two compared sources, Left or Right is nil
+// if the line is only on the other side
+type SideBySideRow struct {
+	Left    *string
+	Right   *string
+	Changed bool
+}
+
+type PlagiarismComparison struct {
+	SolutionA *Solution
+	SolutionB *Solution
+	Rows      []*SideBySideRow
+}
@@ -3,12 +3,41 @@ package models
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/Vyacheslav1557/tester/pkg"
 	"time"
 )
 
 type Meta struct {
-	Count int      `json:"count"`
-	Names []string `json:"names"` // e.g "01", "02", "03"
+	Count   int         `json:"count"`
+	Names   []string    `json:"names"`             // e.g "01", "02", "03", in the order of testing
+	Groups  []TestGroup `json:"groups,omitempty"`  // scoring groups of IOI problems, may be empty
+	Checker *Checker    `json:"checker,omitempty"` // nil if the outputs are compared token by token
+}
+
+// Checker is the testlib checker of a problem. Its source and the headers
+// it includes are kept in the checker directory of the tests archive.
+type Checker struct {
+	Language LanguageName `json:"language"`
+	Source   string       `json:"source"` // file name in the checker directory
+}
+
+// PointsPolicy is how the points of a test group are given, as in polygon packages
+type PointsPolicy string
+
+const (
+	PointsCompleteGroup PointsPolicy = "complete-group" // only if every test of the group has passed
+	PointsEachTest      PointsPolicy = "each-test"      // for every passed test on its own
+)
+
+// TestGroup is a set of tests scored together. The points of a complete group
+// are given only if every test of the group has passed, each-test groups give
+// the points of their passed tests. Groups without a policy are complete groups.
+type TestGroup struct {
+	Name       string       `json:"name"` // empty for the tests with points outside the groups
+	Points     int32        `json:"points"`
+	Policy     PointsPolicy `json:"policy,omitempty"`
+	Tests      []int        `json:"tests"`                 // indices in Meta.Names
+	TestPoints []float64    `json:"test_points,omitempty"` // points of Tests of each-test groups
 }
 
 func (m *Meta) Scan(src interface{}) error {
@@ -50,6 +79,114 @@ func (s *Samples) Scan(src interface{}) error {
 	return json.Unmarshal(data, s)
 }
 
+// Meta describes the tests of a samples-only archive, samples are named "01", "02", ...
+func (s Samples) Meta() Meta {
+	names := make([]string, len(s))
+	for i := range s {
+		names[i] = fmt.Sprintf("%02d", i+1)
+	}
+
+	return Meta{
+		Count: len(names),
+		Names: names,
+	}
+}
+
+// JurySolutionReport is the outcome of running one jury solution of a problem package
+type JurySolutionReport struct {
+	Name       string       `json:"name"` // path inside the package, e.g. "solutions/main.cpp"
+	Tag        string       `json:"tag"`  // polygon tag, e.g. "main", "wrong-answer"
+	Language   LanguageName `json:"language"`
+	State      State        `json:"state"`
+	TimeStat   int32        `json:"time_stat"`
+	MemoryStat int32        `json:"memory_stat"`
+	Passed     bool         `json:"passed"`  // the verdict matches the tag
+	Skipped    bool         `json:"skipped"` // the tag or the language is not supported
+	Message    string       `json:"message,omitempty"`
+}
+
+type JuryReport struct {
+	Solutions []JurySolutionReport `json:"solutions"`
+}
+
+func (r *JuryReport) Scan(src interface{}) error {
+	if src == nil {
+		*r = JuryReport{}
+		return nil
+	}
+
+	// Expect src to be []byte (JSONB data)
+	data, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("expected []byte for JSONB, got %T", src)
+	}
+
+	// Unmarshal JSON into JuryReport
+	return json.Unmarshal(data, r)
+}
+
+// MainFailed reports whether the main jury solution did not get accepted
+func (r JuryReport) MainFailed() bool {
+	for _, s := range r.Solutions {
+		if s.Tag == JuryTagMain && !s.Passed && !s.Skipped {
+			return true
+		}
+	}
+	return false
+}
+
+const JuryTagMain = "main"
+
+// Strategy is how the tests of a solution are run
+type Strategy int32
+
+const (
+	StrategyDefault Strategy = 0 // of contests only, the strategy of the problem is used
+	StrategyICPC    Strategy = 1 // tests in order up to the first failure
+	StrategyIOI     Strategy = 2 // all tests, scored by groups
+)
+
+func (s Strategy) Valid() error {
+	const op = "Strategy.Valid"
+
+	switch s {
+	case StrategyDefault, StrategyICPC, StrategyIOI:
+		return nil
+	}
+
+	return pkg.Wrap(pkg.ErrBadInput, nil, op, "invalid strategy")
+}
+
+// ProblemType is what the participants submit
+type ProblemType int32
+
+const (
+	ProblemStandard   ProblemType = 0 // programs, they are run on the tests
+	ProblemOutputOnly ProblemType = 1 // a zip of the answers to the tests, 01.out, 02.out, ...
+)
+
+func (t ProblemType) Valid() error {
+	const op = "ProblemType.Valid"
+
+	switch t {
+	case ProblemStandard, ProblemOutputOnly:
+		return nil
+	}
+
+	return pkg.Wrap(pkg.ErrBadInput, nil, op, "invalid problem type")
+}
+
+// EffectiveStrategy returns the strategy of the contest unless it is the default one
+func EffectiveStrategy(contest, problem Strategy) Strategy {
+	if contest != StrategyDefault {
+		return contest
+	}
+	if problem != StrategyDefault {
+		return problem
+	}
+	return StrategyICPC
+}
+
 type Problem struct {
 	Id          int32  `db:"id"`
 	Title       string `db:"title"`
@@ -68,8 +205,16 @@ type Problem struct {
 	NotesHtml        string `db:"notes_html"`
 	ScoringHtml      string `db:"scoring_html"`
 
-	Meta    Meta    `db:"meta"`    // JSONB field
-	Samples Samples `db:"samples"` // JSONB field
+	// ValidatorPattern is a regular expression every test input must match entirely
+	ValidatorPattern string `db:"validator_pattern"`
+
+	Strategy Strategy    `db:"strategy"`
+	Type     ProblemType `db:"type"`
+
+	Meta       Meta       `db:"meta"`        // JSONB field
+	Samples    Samples    `db:"samples"`     // JSONB field
+	JuryReport JuryReport `db:"jury_report"` // JSONB field
+	Graders    Graders    `db:"graders"`     // JSONB field
 
 	CreatedAt time.Time `db:"created_at"`
 	UpdatedAt time.Time `db:"updated_at"`
@@ -117,8 +262,15 @@ type ProblemUpdate struct {
 	NotesHtml        *string `db:"notes_html"`
 	ScoringHtml      *string `db:"scoring_html"`
 
-	Meta    *Meta     `db:"meta"`    // JSONB field
-	Samples *[]Sample `db:"samples"` // JSONB field
+	ValidatorPattern *string `db:"validator_pattern"`
+
+	Strategy *Strategy    `db:"strategy"`
+	Type     *ProblemType `db:"type"`
+
+	Meta       *Meta       `db:"meta"`        // JSONB field
+	Samples    *[]Sample   `db:"samples"`     // JSONB field
+	JuryReport *JuryReport `db:"jury_report"` // JSONB field
+	Graders    *Graders    `db:"graders"`     // JSONB field
 }
 
 type ProblemStatement struct {
@@ -1,7 +1,14 @@
 package models
 
 import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"github.com/Vyacheslav1557/tester/pkg"
+	"sort"
+	"sync"
 	"time"
 )
 
@@ -11,34 +18,100 @@ const (
 	Golang LanguageName = 10
 	Cpp    LanguageName = 20
 	Python LanguageName = 30
+	Java   LanguageName = 40
+	Kotlin LanguageName = 50
 )
 
+var (
+	languagesMu sync.RWMutex
+	languages   map[LanguageName]bool
+)
+
+// SetLanguages replaces the languages accepted by Valid, it is called with the
+// enabled languages of the registry in use, none are accepted until then
+func SetLanguages(names []LanguageName) {
+	set := make(map[LanguageName]bool, len(names))
+	for _, n := range names {
+		set[n] = true
+	}
+
+	languagesMu.Lock()
+	defer languagesMu.Unlock()
+
+	languages = set
+}
+
+// EnabledLanguages returns the languages accepted by Valid ordered by id
+func EnabledLanguages() []LanguageName {
+	languagesMu.RLock()
+	defer languagesMu.RUnlock()
+
+	res := make([]LanguageName, 0, len(languages))
+	for n := range languages {
+		res = append(res, n)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i] < res[j]
+	})
+
+	return res
+}
+
 func (n LanguageName) Valid() error {
 	const op = "LanguageName.Valid"
 
-	switch n {
-	case Golang, Cpp, Python:
-		return nil
-	default:
+	languagesMu.RLock()
+	defer languagesMu.RUnlock()
+
+	if !languages[n] {
 		return pkg.Wrap(pkg.ErrBadInput, nil, op, "invalid language")
 	}
+
+	return nil
 }
 
 type State int32
 
 const (
-	Saved State = 1 // saved to db
+	Saved           State = 1 // saved to db
+	JudgementFailed State = 2 // the judge has failed to test the solution, it waits for a rejudge
 
+	// rejection verdicts are 101-199, the monitor counts them as failed attempts
 	GotCE State = 101 // compilation error
 	GotTL State = 102 // time limit exceeded
 	GotML State = 103 // memory limit exceeded
 	GotRE State = 104 // runtime error
 	GotPE State = 105 // presentation error
 	GotWA State = 106 // wrong answer
+	GotOL State = 107 // output limit exceeded
+	GotIL State = 108 // idleness limit exceeded
 
 	Accepted State = 200 // accepted
 )
 
+var stateNames = map[State]string{
+	Saved:           "saved",
+	JudgementFailed: "judgement failed",
+	GotCE:           "CE",
+	GotTL:           "TL",
+	GotML:           "ML",
+	GotRE:           "RE",
+	GotPE:           "PE",
+	GotWA:           "WA",
+	GotOL:           "OL",
+	GotIL:           "IL",
+	Accepted:        "OK",
+}
+
+// String returns the short name of the verdict, e.g. "WA"
+func (s State) String() string {
+	if name, ok := stateNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("State(%d)", int32(s))
+}
+
 type Solution struct {
 	Id int32 `db:"id"`
 
@@ -46,6 +119,13 @@ type Solution struct {
 	Username string `db:"username"`
 
 	Solution string `db:"solution"`
+	// object storage key of the zip of a multi-file solution, Solution is empty then
+	ArchiveKey *string `db:"archive_key"`
+
+	// object storage key of the source, nil for the sources still kept in the database
+	SourceKey  *string `db:"source_key"`
+	SourceHash string  `db:"source_hash"` // hex SHA-256 of the source
+	SourceSize int32   `db:"source_size"` // bytes
 
 	State      State        `db:"state"`
 	Score      int32        `db:"score"`
@@ -53,6 +133,10 @@ type Solution struct {
 	TimeStat   int32        `db:"time_stat"`
 	MemoryStat int32        `db:"memory_stat"`
 	Language   LanguageName `db:"language"`
+	Pretest    bool         `db:"pretest"`     // samples-only run, not counted in the monitor
+	FailedTest int32        `db:"failed_test"` // 1-based number of the test of the verdict, zero if none
+
+	Diagnostics Diagnostics `db:"diagnostics"`
 
 	ProblemId    int32  `db:"problem_id"`
 	ProblemTitle string `db:"problem_title"`
@@ -66,20 +150,91 @@ type Solution struct {
 	CreatedAt time.Time `db:"created_at"`
 }
 
+// Diagnostics is what the judge has to say about a solution. The compile log is
+// shown to the author, the rest is about the failed test and is for the staff only.
+type Diagnostics struct {
+	CompileLog string `json:"compile_log,omitempty"`
+	Stderr     string `json:"stderr,omitempty"`
+	ExitCode   int32  `json:"exit_code,omitempty"`
+	Signal     int32  `json:"signal,omitempty"` // the program was killed by it, zero otherwise
+	Error      string `json:"error,omitempty"`  // of the judge on JudgementFailed
+}
+
+func (d *Diagnostics) Scan(src interface{}) error {
+	if src == nil {
+		*d = Diagnostics{}
+		return nil
+	}
+
+	// Expect src to be []byte (JSONB data)
+	data, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("expected []byte for JSONB, got %T", src)
+	}
+
+	return json.Unmarshal(data, d)
+}
+
+func (d Diagnostics) Value() (driver.Value, error) {
+	return json.Marshal(d)
+}
+
+// JudgeQueue is the state of the judge queue of a priority
+type JudgeQueue struct {
+	Priority    string
+	Depth       int32         // solution runs waiting for a worker
+	OldestWait  time.Duration // of the run waiting the longest
+	AverageWait time.Duration // of the runs started so far
+}
+
 type SolutionUpdate struct {
-	State      State
-	Score      int32
-	TimeStat   int32
-	MemoryStat int32
+	State       State
+	Score       int32
+	TimeStat    int32
+	MemoryStat  int32
+	FailedTest  int32
+	Diagnostics Diagnostics
 }
 
 type SolutionCreation struct {
 	Solution  string
+	Archive   []byte // zip of a multi-file solution, Solution is empty then
 	ProblemId int32
 	ContestId int32
 	UserId    int32
 	Language  LanguageName
 	Penalty   int32
+	Pretest   bool
+	Unlimited bool // skips the submission limit and the duplicate check, e.g. for teachers
+
+	// set by the use case from Solution
+	SourceHash string
+	SourceSize int32
+}
+
+type SolutionRun struct {
+	UserId    int32
+	ContestId *int32 // required with ProblemId
+	ProblemId *int32
+	Language  LanguageName
+	Solution  string
+	Input     string
+}
+
+type SolutionRunResult struct {
+	State      State // zero if the program ran within the limits and exited normally
+	Stdout     string
+	Stderr     string
+	ExitCode   int32
+	TimeStat   int32
+	MemoryStat int32
+}
+
+// SolutionsDiff is a unified diff turning the source of From into the source of To
+type SolutionsDiff struct {
+	From *Solution
+	To   *Solution
+	Diff string
 }
 
 type SolutionsListItem struct {
@@ -94,6 +249,8 @@ type SolutionsListItem struct {
 	TimeStat   int32        `db:"time_stat"`
 	MemoryStat int32        `db:"memory_stat"`
 	Language   LanguageName `db:"language"`
+	Pretest    bool         `db:"pretest"`     // samples-only run, not counted in the monitor
+	FailedTest int32        `db:"failed_test"` // 1-based number of the test of the verdict, zero if none
 
 	ProblemId    int32  `db:"problem_id"`
 	ProblemTitle string `db:"problem_title"`
@@ -126,3 +283,15 @@ type SolutionsFilter struct {
 func (f SolutionsFilter) Offset() int32 {
 	return (f.Page - 1) * f.PageSize
 }
+
+// SourceHash returns the hex SHA-256 the sources are compared and checked by
+func SourceHash(source []byte) string {
+	sum := sha256.Sum256(source)
+	return hex.EncodeToString(sum[:])
+}
+
+// LegacySource is a source still kept in the database
+type LegacySource struct {
+	Id       int32  `db:"id"`
+	Solution string `db:"solution"`
+}
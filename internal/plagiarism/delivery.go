@@ -0,0 +1,11 @@
+package plagiarism
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+type PlagiarismHandlers interface {
+	CheckPlagiarism(c *fiber.Ctx, contestId int32, problemId int32) error
+	GetPlagiarismReport(c *fiber.Ctx, contestId int32, problemId int32) error
+	ComparePlagiarism(c *fiber.Ctx, solutionA int32, solutionB int32) error
+}
@@ -0,0 +1,149 @@
+package rest
+
+import (
+	"context"
+	testerv1 "github.com/Vyacheslav1557/tester/contracts/tester/v1"
+	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/Vyacheslav1557/tester/internal/plagiarism"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handlers struct {
+	plagiarismUC plagiarism.UseCase
+}
+
+func NewHandlers(plagiarismUC plagiarism.UseCase) *Handlers {
+	return &Handlers{
+		plagiarismUC: plagiarismUC,
+	}
+}
+
+const (
+	sessionKey = "session"
+)
+
+func sessionFromCtx(ctx context.Context) (*models.Session, error) {
+	const op = "sessionFromCtx"
+
+	session, ok := ctx.Value(sessionKey).(*models.Session)
+	if !ok {
+		return nil, pkg.Wrap(pkg.ErrUnauthenticated, nil, op, "")
+	}
+
+	return session, nil
+}
+
+// CheckPlagiarism compares the accepted solutions of a contest problem once again
+func (h *Handlers) CheckPlagiarism(c *fiber.Ctx, contestId int32, problemId int32) error {
+	ctx := c.Context()
+
+	session, err := sessionFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch session.Role {
+	case models.RoleAdmin, models.RoleTeacher:
+		err = h.plagiarismUC.CheckPlagiarism(ctx, contestId, problemId)
+		if err != nil {
+			return err
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	default:
+		return pkg.NoPermission
+	}
+}
+
+func (h *Handlers) GetPlagiarismReport(c *fiber.Ctx, contestId int32, problemId int32) error {
+	ctx := c.Context()
+
+	session, err := sessionFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch session.Role {
+	case models.RoleAdmin, models.RoleTeacher:
+		report, err := h.plagiarismUC.GetReport(ctx, contestId, problemId)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(GetPlagiarismReportResponseDTO(report))
+	default:
+		return pkg.NoPermission
+	}
+}
+
+func (h *Handlers) ComparePlagiarism(c *fiber.Ctx, solutionA int32, solutionB int32) error {
+	ctx := c.Context()
+
+	session, err := sessionFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch session.Role {
+	case models.RoleAdmin, models.RoleTeacher:
+		comparison, err := h.plagiarismUC.Compare(ctx, solutionA, solutionB)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(ComparePlagiarismResponseDTO(comparison))
+	default:
+		return pkg.NoPermission
+	}
+}
+
+func GetPlagiarismReportResponseDTO(report *models.PlagiarismReport) testerv1.GetPlagiarismReportResponse {
+	resp := testerv1.GetPlagiarismReportResponse{
+		ContestId: report.ContestId,
+		ProblemId: report.ProblemId,
+		Pairs:     make([]testerv1.PlagiarismPair, len(report.Pairs)),
+	}
+
+	for i, p := range report.Pairs {
+		resp.Pairs[i] = testerv1.PlagiarismPair{
+			SolutionA:  p.SolutionA,
+			UserA:      p.UserA,
+			UsernameA:  p.UsernameA,
+			SolutionB:  p.SolutionB,
+			UserB:      p.UserB,
+			UsernameB:  p.UsernameB,
+			Similarity: p.Similarity,
+			CreatedAt:  p.CreatedAt,
+		}
+	}
+
+	return resp
+}
+
+func ComparePlagiarismResponseDTO(comparison *models.PlagiarismComparison) testerv1.ComparePlagiarismResponse {
+	SolutionSideDTO := func(s *models.Solution) testerv1.PlagiarismSolution {
+		return testerv1.PlagiarismSolution{
+			Id:       s.Id,
+			UserId:   s.UserId,
+			Username: s.Username,
+			Language: int32(s.Language),
+		}
+	}
+
+	resp := testerv1.ComparePlagiarismResponse{
+		SolutionA: SolutionSideDTO(comparison.SolutionA),
+		SolutionB: SolutionSideDTO(comparison.SolutionB),
+		Rows:      make([]testerv1.SideBySideRow, len(comparison.Rows)),
+	}
+
+	for i, row := range comparison.Rows {
+		resp.Rows[i] = testerv1.SideBySideRow{
+			Left:    row.Left,
+			Right:   row.Right,
+			Changed: row.Changed,
+		}
+	}
+
+	return resp
+}
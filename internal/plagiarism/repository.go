@@ -0,0 +1,12 @@
+package plagiarism
+
+import (
+	"context"
+	"github.com/Vyacheslav1557/tester/internal/models"
+)
+
+type Repository interface {
+	ListCandidates(ctx context.Context, contestId, problemId int32) ([]*models.PlagiarismCandidate, error)
+	ReplacePairs(ctx context.Context, contestId, problemId int32, pairs []*models.PlagiarismPair) error
+	ListPairs(ctx context.Context, contestId, problemId int32) ([]*models.PlagiarismPair, error)
+}
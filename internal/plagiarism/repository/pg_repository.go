@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"github.com/jmoiron/sqlx"
+)
+
+type PgRepository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) *PgRepository {
+	return &PgRepository{
+		db: db,
+	}
+}
+
+// the last accepted solution of every participant, samples-only runs are not solutions
+// and archives have no source to compare
+const ListCandidatesQuery = `
+SELECT DISTINCT ON (s.user_id) s.id,
+                               s.user_id,
+                               s.language,
+                               COALESCE(s.solution, '') solution,
+                               s.source_key,
+                               s.source_hash,
+                               s.source_size
+FROM solutions s
+WHERE s.contest_id = $1 AND s.problem_id = $2 AND s.state = 200 AND NOT s.pretest AND s.user_id IS NOT NULL
+  AND s.archive_key IS NULL
+ORDER BY s.user_id, s.id DESC`
+
+func (r *PgRepository) ListCandidates(ctx context.Context, contestId, problemId int32) ([]*models.PlagiarismCandidate, error) {
+	const op = "Repository.ListCandidates"
+
+	candidates := make([]*models.PlagiarismCandidate, 0)
+	err := r.db.SelectContext(ctx, &candidates, ListCandidatesQuery, contestId, problemId)
+	if err != nil {
+		return nil, pkg.HandlePgErr(err, op)
+	}
+
+	return candidates, nil
+}
+
+const (
+	DeletePairsQuery = "DELETE FROM plagiarism_pairs WHERE contest_id = $1 AND problem_id = $2"
+	CreatePairQuery  = `
+INSERT INTO plagiarism_pairs (contest_id, problem_id, solution_a, solution_b, similarity)
+VALUES ($1, $2, $3, $4, $5)`
+)
+
+// ReplacePairs drops the pairs of the previous check and saves the new ones
+func (r *PgRepository) ReplacePairs(ctx context.Context, contestId, problemId int32, pairs []*models.PlagiarismPair) error {
+	const op = "Repository.ReplacePairs"
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return pkg.HandlePgErr(err, op)
+	}
+
+	_, err = tx.ExecContext(ctx, DeletePairsQuery, contestId, problemId)
+	if err != nil {
+		return pkg.HandlePgErr(errors.Join(err, tx.Rollback()), op)
+	}
+
+	for _, pair := range pairs {
+		_, err = tx.ExecContext(ctx, CreatePairQuery, contestId, problemId, pair.SolutionA, pair.SolutionB, pair.Similarity)
+		if err != nil {
+			return pkg.HandlePgErr(errors.Join(err, tx.Rollback()), op)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return pkg.HandlePgErr(err, op)
+	}
+
+	return nil
+}
+
+const ListPairsQuery = `
+SELECT pp.solution_a,
+       COALESCE(sa.user_id, 0)   user_a,
+       COALESCE(ua.username, '') username_a,
+
+       pp.solution_b,
+       COALESCE(sb.user_id, 0)   user_b,
+       COALESCE(ub.username, '') username_b,
+
+       pp.similarity,
+       pp.created_at
+FROM plagiarism_pairs pp
+         LEFT JOIN solutions sa ON pp.solution_a = sa.id
+         LEFT JOIN users ua ON sa.user_id = ua.id
+         LEFT JOIN solutions sb ON pp.solution_b = sb.id
+         LEFT JOIN users ub ON sb.user_id = ub.id
+WHERE pp.contest_id = $1 AND pp.problem_id = $2
+ORDER BY pp.similarity DESC, pp.solution_a, pp.solution_b`
+
+func (r *PgRepository) ListPairs(ctx context.Context, contestId, problemId int32) ([]*models.PlagiarismPair, error) {
+	const op = "Repository.ListPairs"
+
+	pairs := make([]*models.PlagiarismPair, 0)
+	err := r.db.SelectContext(ctx, &pairs, ListPairsQuery, contestId, problemId)
+	if err != nil {
+		return nil, pkg.HandlePgErr(err, op)
+	}
+
+	return pairs, nil
+}
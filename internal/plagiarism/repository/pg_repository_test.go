@@ -0,0 +1,133 @@
+package repository_test
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/Vyacheslav1557/tester/internal/plagiarism/repository"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// setupTestDB creates a mocked sqlx.DB and sqlmock instance for runner.
+func setupTestDB(t *testing.T) (*sqlx.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	return sqlxDB, mock
+}
+
+func TestRepository_ListCandidates(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewRepository(db)
+
+	t.Run("success", func(t *testing.T) {
+		ctx := context.Background()
+
+		key := "solutions/3/source"
+		candidate := &models.PlagiarismCandidate{
+			Id:         3,
+			UserId:     7,
+			Language:   models.Cpp,
+			SourceKey:  &key,
+			SourceHash: models.SourceHash([]byte("int main() {}")),
+			SourceSize: 13,
+		}
+
+		mock.ExpectQuery(repository.ListCandidatesQuery).
+			WithArgs(int32(1), int32(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "language", "solution", "source_key", "source_hash", "source_size"}).
+				AddRow(candidate.Id, candidate.UserId, candidate.Language, "", key, candidate.SourceHash, candidate.SourceSize))
+
+		candidates, err := repo.ListCandidates(ctx, 1, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []*models.PlagiarismCandidate{candidate}, candidates)
+	})
+}
+
+func TestRepository_ReplacePairs(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewRepository(db)
+
+	t.Run("success", func(t *testing.T) {
+		ctx := context.Background()
+
+		pairs := []*models.PlagiarismPair{
+			{SolutionA: 3, SolutionB: 5, Similarity: 0.9},
+			{SolutionA: 4, SolutionB: 5, Similarity: 0.4},
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectExec(repository.DeletePairsQuery).
+			WithArgs(int32(1), int32(2)).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		for _, p := range pairs {
+			mock.ExpectExec(repository.CreatePairQuery).
+				WithArgs(int32(1), int32(2), p.SolutionA, p.SolutionB, p.Similarity).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		mock.ExpectCommit()
+
+		err := repo.ReplacePairs(ctx, 1, 2, pairs)
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		ctx := context.Background()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(repository.DeletePairsQuery).
+			WithArgs(int32(1), int32(2)).
+			WillReturnError(sqlmock.ErrCancelled)
+		mock.ExpectRollback()
+
+		err := repo.ReplacePairs(ctx, 1, 2, nil)
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRepository_ListPairs(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewRepository(db)
+
+	t.Run("success", func(t *testing.T) {
+		ctx := context.Background()
+
+		pair := &models.PlagiarismPair{
+			SolutionA:  3,
+			UserA:      7,
+			UsernameA:  "alice",
+			SolutionB:  5,
+			UserB:      8,
+			UsernameB:  "bob",
+			Similarity: 0.9,
+			CreatedAt:  time.Now(),
+		}
+
+		mock.ExpectQuery(repository.ListPairsQuery).
+			WithArgs(int32(1), int32(2)).
+			WillReturnRows(sqlmock.NewRows([]string{
+				"solution_a", "user_a", "username_a",
+				"solution_b", "user_b", "username_b",
+				"similarity", "created_at",
+			}).AddRow(
+				pair.SolutionA, pair.UserA, pair.UsernameA,
+				pair.SolutionB, pair.UserB, pair.UsernameB,
+				pair.Similarity, pair.CreatedAt,
+			))
+
+		pairs, err := repo.ListPairs(ctx, 1, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []*models.PlagiarismPair{pair}, pairs)
+	})
+}
@@ -0,0 +1,12 @@
+package plagiarism
+
+import (
+	"context"
+	"github.com/Vyacheslav1557/tester/internal/models"
+)
+
+type UseCase interface {
+	CheckPlagiarism(ctx context.Context, contestId, problemId int32) error
+	GetReport(ctx context.Context, contestId, problemId int32) (*models.PlagiarismReport, error)
+	Compare(ctx context.Context, solutionA, solutionB int32) (*models.PlagiarismComparison, error)
+}
@@ -0,0 +1,152 @@
+package usecase
+
+import (
+	"context"
+	"github.com/Vyacheslav1557/tester/internal/contests"
+	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/Vyacheslav1557/tester/internal/plagiarism"
+	"github.com/Vyacheslav1557/tester/internal/solutions"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"github.com/Vyacheslav1557/tester/pkg/diff"
+	fingerprints "github.com/Vyacheslav1557/tester/pkg/plagiarism"
+)
+
+// pairs less similar than that are not worth a look
+const threshold = 0.3
+
+type UseCase struct {
+	plagiarismRepo plagiarism.Repository
+	solutionsUC    solutions.UseCase
+	contestsUC     contests.UseCase
+}
+
+func NewUseCase(
+	plagiarismRepo plagiarism.Repository,
+	solutionsUC solutions.UseCase,
+	contestsUC contests.UseCase,
+) *UseCase {
+	return &UseCase{
+		plagiarismRepo: plagiarismRepo,
+		solutionsUC:    solutionsUC,
+		contestsUC:     contestsUC,
+	}
+}
+
+// CheckPlagiarism compares the last accepted solutions of the participants
+// with each other and replaces the pairs found by the previous check
+func (uc *UseCase) CheckPlagiarism(ctx context.Context, contestId, problemId int32) error {
+	// the problem must be in the contest
+	_, err := uc.contestsUC.GetContestProblem(ctx, contestId, problemId)
+	if err != nil {
+		return err
+	}
+
+	candidates, err := uc.plagiarismRepo.ListCandidates(ctx, contestId, problemId)
+	if err != nil {
+		return err
+	}
+
+	docs := make([]fingerprints.Document, 0, len(candidates))
+	for _, c := range candidates {
+		if c.SourceKey != nil {
+			c.Solution, err = uc.solutionsUC.ReadSource(ctx, *c.SourceKey, c.SourceSize, c.SourceHash)
+			if err != nil {
+				return err
+			}
+		}
+
+		docs = append(docs, fingerprints.Document{
+			Id:           c.Id,
+			Fingerprints: fingerprints.Fingerprint(fingerprints.Tokenize(c.Language, c.Solution)),
+		})
+	}
+
+	found := fingerprints.Compare(docs, threshold)
+
+	pairs := make([]*models.PlagiarismPair, 0, len(found))
+	for _, p := range found {
+		pairs = append(pairs, &models.PlagiarismPair{
+			SolutionA:  p.A,
+			SolutionB:  p.B,
+			Similarity: p.Similarity,
+		})
+	}
+
+	return uc.plagiarismRepo.ReplacePairs(ctx, contestId, problemId, pairs)
+}
+
+func (uc *UseCase) GetReport(ctx context.Context, contestId, problemId int32) (*models.PlagiarismReport, error) {
+	pairs, err := uc.plagiarismRepo.ListPairs(ctx, contestId, problemId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PlagiarismReport{
+		ContestId: contestId,
+		ProblemId: problemId,
+		Pairs:     pairs,
+	}, nil
+}
+
+// Compare puts two solutions of the same problem side by side
+func (uc *UseCase) Compare(ctx context.Context, solutionA, solutionB int32) (*models.PlagiarismComparison, error) {
+	const op = "UseCase.Compare"
+
+	a, err := uc.solutionsUC.GetSolution(ctx, solutionA)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := uc.solutionsUC.GetSolution(ctx, solutionB)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.ContestId != b.ContestId || a.ProblemId != b.ProblemId {
+		return nil, pkg.Wrap(pkg.ErrBadInput, nil, op, "solutions of different problems")
+	}
+
+	left, right := diff.SplitLines(a.Solution), diff.SplitLines(b.Solution)
+
+	return &models.PlagiarismComparison{
+		SolutionA: a,
+		SolutionB: b,
+		Rows:      sideBySide(left, right, diff.Lines(left, right, diff.IgnoreWhitespace)),
+	}, nil
+}
+
+// sideBySide puts the deleted and the inserted lines of a change next to each other,
+// the equal lines are shown as they are on either side
+func sideBySide(left, right []string, edits []diff.Edit) []*models.SideBySideRow {
+	rows := make([]*models.SideBySideRow, 0, len(edits))
+
+	var deleted, inserted []string
+	flush := func() {
+		for i := 0; i < max(len(deleted), len(inserted)); i++ {
+			row := &models.SideBySideRow{Changed: true}
+			if i < len(deleted) {
+				row.Left = &deleted[i]
+			}
+			if i < len(inserted) {
+				row.Right = &inserted[i]
+			}
+			rows = append(rows, row)
+		}
+		deleted, inserted = nil, nil
+	}
+
+	for _, e := range edits {
+		switch e.Op {
+		case diff.Delete:
+			deleted = append(deleted, e.Text)
+		case diff.Insert:
+			inserted = append(inserted, e.Text)
+		default:
+			flush()
+			rows = append(rows, &models.SideBySideRow{Left: &left[e.Old], Right: &right[e.New]})
+		}
+	}
+	flush()
+
+	return rows
+}
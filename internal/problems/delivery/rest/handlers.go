@@ -167,6 +167,11 @@ func (h *Handlers) UpdateProblem(c *fiber.Ctx, id int32) error {
 			OutputFormat: req.OutputFormat,
 			Notes:        req.Notes,
 			Scoring:      req.Scoring,
+
+			ValidatorPattern: req.ValidatorPattern,
+			Strategy:         strategyP(req.Strategy),
+			Type:             problemTypeP(req.Type),
+			Graders:          gradersP(req.Graders),
 		})
 
 		if err != nil {
@@ -254,16 +259,100 @@ func ProblemDTO(p *models.Problem) *testerv1.Problem {
 		NotesHtml:        p.NotesHtml,
 		ScoringHtml:      p.ScoringHtml,
 
+		ValidatorPattern: p.ValidatorPattern,
+		Strategy:         int32(p.Strategy),
+		Type:             int32(p.Type),
+		Graders:          GradersDTO(p.Graders),
+
 		//Meta:    MetaDTO(p.Meta),
-		//Samples: SamplesDTO(p.Samples),
+		Samples:    SamplesDTO(p.Samples),
+		JuryReport: JuryReportDTO(p.JuryReport),
 
 		CreatedAt: p.CreatedAt,
 		UpdatedAt: p.UpdatedAt,
 	}
 }
 
+func strategyP(s *int32) *models.Strategy {
+	if s == nil {
+		return nil
+	}
+
+	strategy := models.Strategy(*s)
+	return &strategy
+}
+
+func SamplesDTO(s models.Samples) []testerv1.Sample {
+	samples := make([]testerv1.Sample, len(s))
+	for i, sample := range s {
+		samples[i] = testerv1.Sample{
+			Input:  sample.Input,
+			Output: sample.Output,
+		}
+	}
+	return samples
+}
+
+func JuryReportDTO(r models.JuryReport) testerv1.JuryReport {
+	solutions := make([]testerv1.JurySolutionReport, len(r.Solutions))
+	for i, s := range r.Solutions {
+		solutions[i] = testerv1.JurySolutionReport{
+			Name:       s.Name,
+			Tag:        s.Tag,
+			Language:   int32(s.Language),
+			State:      int32(s.State),
+			TimeStat:   s.TimeStat,
+			MemoryStat: s.MemoryStat,
+			Passed:     s.Passed,
+			Skipped:    s.Skipped,
+			Message:    s.Message,
+		}
+	}
+	return testerv1.JuryReport{
+		Solutions:  solutions,
+		MainFailed: r.MainFailed(),
+	}
+}
+
 //func MetaDTO(m models.Meta) testerv1.Meta {
 //	return testerv1.Meta{
 //		Author: m.Author,
 //	}
 //}
+
+func problemTypeP(t *int32) *models.ProblemType {
+	if t == nil {
+		return nil
+	}
+
+	problemType := models.ProblemType(*t)
+	return &problemType
+}
+
+func gradersP(g *[]testerv1.Grader) *models.Graders {
+	if g == nil {
+		return nil
+	}
+
+	graders := make(models.Graders, len(*g))
+	for i, grader := range *g {
+		graders[i] = models.Grader{
+			Language: models.LanguageName(grader.Language),
+			Source:   grader.Source,
+			Template: grader.Template,
+		}
+	}
+	return &graders
+}
+
+func GradersDTO(g models.Graders) []testerv1.Grader {
+	graders := make([]testerv1.Grader, len(g))
+	for i, grader := range g {
+		graders[i] = testerv1.Grader{
+			Language: int32(grader.Language),
+			Source:   grader.Source,
+			Template: grader.Template,
+		}
+	}
+	return graders
+}
@@ -166,7 +166,14 @@ SET title              = COALESCE($2, title),
     scoring_html       = COALESCE($14, scoring_html),
     
     meta               = COALESCE($15, meta),
-	samples            = COALESCE($16, samples)
+	samples            = COALESCE($16, samples),
+	jury_report        = COALESCE($17, jury_report),
+
+    validator_pattern  = COALESCE($18, validator_pattern),
+
+    strategy           = COALESCE($19, strategy),
+    type               = COALESCE($20, type),
+    graders            = COALESCE($21, graders)
 
 WHERE id=$1`
 )
@@ -196,6 +203,13 @@ func (r *Repository) UpdateProblem(ctx context.Context, q problems.Querier, id i
 
 		problem.Meta,
 		problem.Samples,
+		problem.JuryReport,
+
+		problem.ValidatorPattern,
+
+		problem.Strategy,
+		problem.Type,
+		problem.Graders,
 	)
 	if err != nil {
 		return pkg.HandlePgErr(err, op)
@@ -14,4 +14,5 @@ type UseCase interface {
 	UpdateProblem(ctx context.Context, id int32, problem *models.ProblemUpdate) error
 	UploadProblem(ctx context.Context, id int32, r io.ReaderAt, size int64) error
 	DownloadTestsArchive(ctx context.Context, id int32) (string, error)
+	DownloadSamplesArchive(ctx context.Context, id int32) (string, error)
 }
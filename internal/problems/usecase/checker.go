@@ -0,0 +1,104 @@
+package usecase
+
+import (
+	"archive/zip"
+	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"github.com/Vyacheslav1557/tester/pkg/tester"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// addChecker puts the checker declared in problem.xml into the tests archive, along with
+// the headers lying next to its source. Packages without a checker get none, their outputs
+// are compared token by token.
+func addChecker(archive *tester.ArchiveReader, dst *zip.Writer) (*models.Checker, error) {
+	const op = "addChecker"
+
+	problem, files, err := readDescriptor(archive)
+	if err != nil || problem == nil || problem.Checker == nil {
+		return nil, err
+	}
+
+	checker, err := readProgram(archive, files, problem.Checker.Source, "checker")
+	if err != nil {
+		return nil, err
+	}
+
+	source := path.Base(problem.Checker.Source.Path)
+	entries := map[string][]byte{source: checker.source}
+	for name, dependency := range checker.dependencies {
+		entries[name] = dependency
+	}
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		w, err := dst.Create(path.Join(tester.CheckerDir, name))
+		if err != nil {
+			return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to add checker")
+		}
+
+		if _, err := w.Write(entries[name]); err != nil {
+			return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to add checker")
+		}
+	}
+
+	return &models.Checker{Language: checker.lang, Source: source}, nil
+}
+
+// copyChecker copies the checker of the tests archive to another one, e.g. to the samples
+func copyChecker(testsPath string, dst *zip.Writer) error {
+	const op = "copyChecker"
+
+	tests, err := os.Open(testsPath)
+	if err != nil {
+		return pkg.Wrap(pkg.ErrInternal, err, op, "failed to open tests archive")
+	}
+	defer tests.Close()
+
+	stat, err := tests.Stat()
+	if err != nil {
+		return pkg.Wrap(pkg.ErrInternal, err, op, "failed to stat tests archive")
+	}
+
+	archive, err := zip.NewReader(tests, stat.Size())
+	if err != nil {
+		return pkg.Wrap(pkg.ErrInternal, err, op, "failed to read tests archive")
+	}
+
+	for _, file := range archive.File {
+		if !strings.HasPrefix(file.Name, tester.CheckerDir+"/") {
+			continue
+		}
+
+		if err := copyZipFile(file, dst); err != nil {
+			return pkg.Wrap(pkg.ErrInternal, err, op, "failed to copy checker")
+		}
+	}
+
+	return nil
+}
+
+func copyZipFile(src *zip.File, dst *zip.Writer) error {
+	r, err := src.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	w, err := dst.Create(src.Name)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, r)
+	return err
+}
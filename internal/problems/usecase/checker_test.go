@@ -0,0 +1,112 @@
+package usecase_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/Vyacheslav1557/tester/internal/problems/usecase"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"github.com/Vyacheslav1557/tester/pkg/tester"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+)
+
+// packageArchive is a polygon package with the given files
+func packageArchive(t *testing.T, files map[string]string) *tester.ArchiveReader {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	w := zip.NewWriter(buf)
+	for name, body := range files {
+		f, err := w.Create(name)
+		require.NoError(t, err)
+		_, err = f.Write([]byte(body))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	archive, err := tester.NewArchiveReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()), tester.DefaultArchiveLimits)
+	require.NoError(t, err)
+	return archive
+}
+
+// zipEntries reads the entries of a zip by name
+func zipEntries(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	entries := make(map[string]string)
+	for _, file := range r.File {
+		rc, err := file.Open()
+		require.NoError(t, err)
+		body, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		entries[file.Name] = string(body)
+	}
+	return entries
+}
+
+func TestAddChecker(t *testing.T) {
+	t.Parallel()
+
+	t.Run("checker", func(t *testing.T) {
+		archive := packageArchive(t, map[string]string{
+			"problem.xml": `<problem><assets><checker name="std::wcmp.cpp" type="testlib">
+				<source path="files/check.cpp" type="cpp.g++17"/>
+			</checker></assets></problem>`,
+			"files/check.cpp":     "#include \"testlib.h\"",
+			"files/testlib.h":     "// testlib",
+			"files/olymp.sty":     "",
+			"solutions/main.cpp":  "int main() {}",
+			"solutions/testlib.h": "// not the checker's",
+			"files/validator.cpp": "#include \"testlib.h\"",
+		})
+
+		buf := &bytes.Buffer{}
+		w := zip.NewWriter(buf)
+		checker, err := usecase.AddChecker(archive, w)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		require.Equal(t, &models.Checker{Language: models.Cpp, Source: "check.cpp"}, checker)
+		require.Equal(t, map[string]string{
+			"checker/check.cpp": "#include \"testlib.h\"",
+			"checker/testlib.h": "// testlib",
+		}, zipEntries(t, buf.Bytes()))
+	})
+
+	t.Run("no checker", func(t *testing.T) {
+		archive := packageArchive(t, map[string]string{"problem.xml": `<problem/>`})
+
+		buf := &bytes.Buffer{}
+		w := zip.NewWriter(buf)
+		checker, err := usecase.AddChecker(archive, w)
+		require.NoError(t, err)
+		require.Nil(t, checker)
+		require.NoError(t, w.Close())
+		require.Empty(t, zipEntries(t, buf.Bytes()))
+	})
+
+	t.Run("unsupported language", func(t *testing.T) {
+		archive := packageArchive(t, map[string]string{
+			"problem.xml":     `<problem><assets><checker><source path="files/check.pas" type="pascal.fpc"/></checker></assets></problem>`,
+			"files/check.pas": "begin end.",
+		})
+
+		_, err := usecase.AddChecker(archive, zip.NewWriter(io.Discard))
+		require.ErrorIs(t, err, pkg.ErrBadInput)
+	})
+
+	t.Run("missing source", func(t *testing.T) {
+		archive := packageArchive(t, map[string]string{
+			"problem.xml": `<problem><assets><checker><source path="files/check.cpp" type="cpp.g++17"/></checker></assets></problem>`,
+		})
+
+		_, err := usecase.AddChecker(archive, zip.NewWriter(io.Discard))
+		require.ErrorIs(t, err, pkg.ErrBadInput)
+	})
+}
@@ -0,0 +1,7 @@
+package usecase
+
+var (
+	AddChecker     = addChecker
+	ReadTestGroups = readTestGroups
+	SortTestNames  = sortTestNames
+)
@@ -0,0 +1,114 @@
+package usecase
+
+import (
+	"fmt"
+	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"github.com/Vyacheslav1557/tester/pkg/tester"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// readTestGroups takes the groups of the "tests" testset of problem.xml.
+// The n-th test of the testset is the test file named n, e.g. "01".
+// Tests with points outside the groups make an unnamed each-test group.
+// Problems without problem.xml or without groups get none.
+func readTestGroups(archive *tester.ArchiveReader, meta *models.Meta) ([]models.TestGroup, error) {
+	const op = "readTestGroups"
+
+	problem, _, err := readDescriptor(archive)
+	if err != nil || problem == nil {
+		return nil, err
+	}
+
+	var testset *testsetXML
+	for i := range problem.Testsets {
+		if problem.Testsets[i].Name == "tests" {
+			testset = &problem.Testsets[i]
+		}
+	}
+	if testset == nil {
+		return nil, nil
+	}
+
+	indices := make(map[int]int, len(meta.Names))
+	for i, name := range meta.Names {
+		if n, err := strconv.Atoi(name); err == nil {
+			indices[n] = i
+		}
+	}
+
+	var groups []models.TestGroup
+	byName := make(map[string]int)
+	points := make(map[string]float64)
+
+	for i, test := range testset.Tests {
+		if test.Group == "" && test.Points == 0 {
+			continue
+		}
+
+		index, ok := indices[i+1]
+		if !ok {
+			return nil, pkg.Wrap(pkg.ErrBadInput, nil, op, fmt.Sprintf("test %d of problem.xml not found", i+1))
+		}
+
+		g, ok := byName[test.Group]
+		if !ok {
+			g = len(groups)
+			byName[test.Group] = g
+
+			policy := models.PointsCompleteGroup
+			if test.Group == "" {
+				policy = models.PointsEachTest
+			}
+			groups = append(groups, models.TestGroup{Name: test.Group, Policy: policy})
+		}
+
+		groups[g].Tests = append(groups[g].Tests, index)
+		groups[g].TestPoints = append(groups[g].TestPoints, test.Points)
+		points[test.Group] += test.Points
+	}
+
+	// the points of a complete group are declared with the group or summed up from its tests,
+	// each-test groups have the points of their tests
+	for _, group := range testset.Groups {
+		g, ok := byName[group.Name]
+		if !ok || group.Name == "" {
+			continue
+		}
+
+		switch policy := models.PointsPolicy(group.PointsPolicy); policy {
+		case "", models.PointsCompleteGroup:
+			if group.Points != nil {
+				points[group.Name] = *group.Points
+			}
+		case models.PointsEachTest:
+			groups[g].Policy = policy
+		default:
+			return nil, pkg.Wrap(pkg.ErrBadInput, nil, op,
+				fmt.Sprintf("unknown points policy %q of group %s", group.PointsPolicy, group.Name))
+		}
+	}
+
+	for i := range groups {
+		groups[i].Points = int32(math.Round(points[groups[i].Name]))
+		if groups[i].Policy != models.PointsEachTest {
+			groups[i].TestPoints = nil
+		}
+	}
+
+	return groups, nil
+}
+
+// sortTestNames orders the tests for testing, numeric names by their value
+func sortTestNames(names []string) {
+	sort.Slice(names, func(i, j int) bool {
+		a, errA := strconv.Atoi(names[i])
+		b, errB := strconv.Atoi(names[j])
+		if errA == nil && errB == nil && a != b {
+			return a < b
+		}
+		return names[i] < names[j]
+	})
+}
@@ -0,0 +1,116 @@
+package usecase_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/Vyacheslav1557/tester/internal/problems/usecase"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"github.com/Vyacheslav1557/tester/pkg/tester"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// descriptorArchive is a package with the problem.xml only, an empty one without a descriptor
+func descriptorArchive(t *testing.T, descriptor string) *tester.ArchiveReader {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	w := zip.NewWriter(buf)
+	if descriptor != "" {
+		f, err := w.Create("problem.xml")
+		require.NoError(t, err)
+		_, err = f.Write([]byte(descriptor))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	archive, err := tester.NewArchiveReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()), tester.DefaultArchiveLimits)
+	require.NoError(t, err)
+	return archive
+}
+
+func TestReadTestGroups(t *testing.T) {
+	t.Parallel()
+
+	meta := &models.Meta{Count: 5, Names: []string{"1", "2", "3", "4", "5"}}
+
+	for name, tc := range map[string]struct {
+		descriptor string
+		expected   []models.TestGroup
+		err        error
+	}{
+		"no descriptor": {},
+		"no groups": {
+			descriptor: `<problem><judging><testset name="tests"><tests>
+				<test/><test/>
+			</tests></testset></judging></problem>`,
+		},
+		"policies": {
+			descriptor: `<problem><judging><testset name="tests">
+				<tests>
+					<test group="0" points="0"/>
+					<test group="1" points="10"/>
+					<test group="1" points="15"/>
+					<test group="2" points="20"/>
+					<test points="5"/>
+				</tests>
+				<groups>
+					<group name="0" points="0" points-policy="complete-group"/>
+					<group name="1" points-policy="each-test"/>
+					<group name="2" points="50"/>
+				</groups>
+			</testset></judging></problem>`,
+			expected: []models.TestGroup{
+				{Name: "0", Points: 0, Policy: models.PointsCompleteGroup, Tests: []int{0}},
+				{Name: "1", Points: 25, Policy: models.PointsEachTest, Tests: []int{1, 2}, TestPoints: []float64{10, 15}},
+				{Name: "2", Points: 50, Policy: models.PointsCompleteGroup, Tests: []int{3}},
+				{Name: "", Points: 5, Policy: models.PointsEachTest, Tests: []int{4}, TestPoints: []float64{5}},
+			},
+		},
+		"unknown policy": {
+			descriptor: `<problem><judging><testset name="tests">
+				<tests><test group="1" points="10"/></tests>
+				<groups><group name="1" points-policy="best-test"/></groups>
+			</testset></judging></problem>`,
+			err: pkg.ErrBadInput,
+		},
+		"missing test": {
+			descriptor: `<problem><judging><testset name="tests"><tests>
+				<test/><test/><test/><test/><test/><test group="1"/>
+			</tests></testset></judging></problem>`,
+			err: pkg.ErrBadInput,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			groups, err := usecase.ReadTestGroups(descriptorArchive(t, tc.descriptor), meta)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, groups)
+		})
+	}
+}
+
+func TestSortTestNames(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		names    []string
+		expected []string
+	}{
+		"numeric": {[]string{"10", "2", "1"}, []string{"1", "2", "10"}},
+		"padded":  {[]string{"010", "002", "01"}, []string{"01", "002", "010"}},
+		"same":    {[]string{"01", "1"}, []string{"01", "1"}},
+		"names":   {[]string{"b", "a", "10"}, []string{"10", "a", "b"}},
+		"mixed":   {[]string{"2", "1a", "1"}, []string{"1", "1a", "2"}},
+		"empty":   {[]string{}, []string{}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			usecase.SortTestNames(tc.names)
+			require.Equal(t, tc.expected, tc.names)
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package usecase
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"github.com/Vyacheslav1557/tester/pkg/tester"
+	"io"
+	"strings"
+)
+
+// polygon problem.xml, only the parts we need
+type problemXML struct {
+	Solutions []struct {
+		Tag    string    `xml:"tag,attr"`
+		Source sourceXML `xml:"source"`
+	} `xml:"assets>solutions>solution"`
+	Validators []struct {
+		Source sourceXML `xml:"source"`
+	} `xml:"assets>validators>validator"`
+	Checker *struct {
+		Source sourceXML `xml:"source"`
+	} `xml:"assets>checker"`
+	Testsets []testsetXML `xml:"judging>testset"`
+}
+
+type testsetXML struct {
+	Name  string `xml:"name,attr"`
+	Tests []struct {
+		Group  string  `xml:"group,attr"`
+		Points float64 `xml:"points,attr"`
+	} `xml:"tests>test"`
+	Groups []struct {
+		Name         string   `xml:"name,attr"`
+		Points       *float64 `xml:"points,attr"`
+		PointsPolicy string   `xml:"points-policy,attr"`
+	} `xml:"groups>group"`
+}
+
+type sourceXML struct {
+	Path string `xml:"path,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type jurySolution struct {
+	name     string
+	tag      string
+	lang     models.LanguageName
+	source   []byte
+	skipping string // reason the solution is not run, if any
+}
+
+// juryExpectations maps a polygon tag to the verdicts which confirm it
+var juryExpectations = map[string][]models.State{
+	models.JuryTagMain:      {models.Accepted},
+	"accepted":              {models.Accepted},
+	"wrong-answer":          {models.GotWA},
+	"presentation-error":    {models.GotPE},
+	"time-limit-exceeded":   {models.GotTL, models.GotIL},
+	"memory-limit-exceeded": {models.GotML},
+	"rejected": {
+		models.GotWA, models.GotPE, models.GotTL, models.GotML, models.GotRE, models.GotOL, models.GotIL,
+	},
+	"time-limit-exceeded-or-accepted":              {models.GotTL, models.GotIL, models.Accepted},
+	"time-limit-exceeded-or-memory-limit-exceeded": {models.GotTL, models.GotIL, models.GotML},
+}
+
+func juryLanguage(sourceType string) (models.LanguageName, bool) {
+	lang := tester.Languages().ByPolygonType(sourceType)
+	if lang == nil {
+		return 0, false
+	}
+	return lang.Id, true
+}
+
+// readDescriptor parses problem.xml of a polygon package, nil if there is none
+func readDescriptor(archive *tester.ArchiveReader) (*problemXML, map[string]*zip.File, error) {
+	const op = "readDescriptor"
+
+	files := make(map[string]*zip.File)
+	for _, file := range archive.Files() {
+		files[file.Name] = file
+	}
+
+	descriptor, ok := files["problem.xml"]
+	if !ok {
+		return nil, files, nil
+	}
+
+	raw, err := readArchiveFile(archive, descriptor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var problem problemXML
+	if err := xml.Unmarshal(raw, &problem); err != nil {
+		return nil, nil, pkg.Wrap(pkg.ErrBadInput, err, op, "failed to parse problem.xml")
+	}
+
+	return &problem, files, nil
+}
+
+// readJurySolutions collects the solutions declared in problem.xml.
+// Packages without problem.xml have no jury solutions.
+func readJurySolutions(archive *tester.ArchiveReader) ([]jurySolution, error) {
+	const op = "readJurySolutions"
+
+	problem, files, err := readDescriptor(archive)
+	if err != nil || problem == nil {
+		return nil, err
+	}
+
+	solutions := make([]jurySolution, 0, len(problem.Solutions))
+	for _, s := range problem.Solutions {
+		solution := jurySolution{
+			name: s.Source.Path,
+			tag:  s.Tag,
+		}
+
+		lang, ok := juryLanguage(s.Source.Type)
+		switch {
+		case juryExpectations[s.Tag] == nil:
+			solution.skipping = fmt.Sprintf("tag %q is not checked", s.Tag)
+		case !ok:
+			solution.skipping = fmt.Sprintf("language %q is not supported", s.Source.Type)
+		}
+
+		if solution.skipping == "" {
+			file, ok := files[s.Source.Path]
+			if !ok {
+				return nil, pkg.Wrap(pkg.ErrBadInput, nil, op,
+					fmt.Sprintf("jury solution %q not found", s.Source.Path))
+			}
+
+			solution.lang = lang
+			solution.source, err = readArchiveFile(archive, file)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		solutions = append(solutions, solution)
+	}
+
+	return solutions, nil
+}
+
+func readArchiveFile(archive *tester.ArchiveReader, file *zip.File) ([]byte, error) {
+	const op = "readArchiveFile"
+
+	rc, err := archive.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		if errors.Is(err, pkg.ErrBadInput) {
+			return nil, err
+		}
+		return nil, pkg.Wrap(pkg.ErrBadInput, err, op, fmt.Sprintf("failed to read %q", file.Name))
+	}
+
+	return b, nil
+}
+
+// judge runs every jury solution against the uploaded tests and checks
+// the verdicts against the solution tags, the way polygon invocations do
+func (u *UseCase) judge(ctx context.Context, packet uploadPacket, solutions []jurySolution) (*models.JuryReport, error) {
+	report := &models.JuryReport{Solutions: make([]models.JurySolutionReport, 0, len(solutions))}
+
+	for _, solution := range solutions {
+		result := models.JurySolutionReport{
+			Name:     solution.name,
+			Tag:      solution.tag,
+			Language: solution.lang,
+		}
+
+		if solution.skipping != "" {
+			result.Skipped = true
+			result.Message = solution.skipping
+			report.Solutions = append(report.Solutions, result)
+			continue
+		}
+
+		states, err := u.invoke(ctx, packet, solution, &result)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Passed = confirms(juryExpectations[solution.tag], states)
+		if !result.Passed {
+			result.Message = fmt.Sprintf("expected %s, got %s", solution.tag, stateNames(states))
+		}
+
+		report.Solutions = append(report.Solutions, result)
+	}
+
+	return report, nil
+}
+
+// invoke tests one jury solution and returns all verdicts it got.
+// A solution which passed every test gets exactly models.Accepted.
+func (u *UseCase) invoke(
+	ctx context.Context,
+	packet uploadPacket,
+	solution jurySolution,
+	result *models.JurySolutionReport,
+) ([]models.State, error) {
+	const op = "UseCase.invoke"
+
+	var states []models.State
+	var internalErr error
+
+	passed := 0
+	failedTest := 0
+	// drain the channel, the tester sends results of every test
+	for msg := range u.tester.Test(ctx, packet, &program{source: solution.source, lang: solution.lang}) {
+		if msg.Metrics != nil {
+			result.TimeStat = max(result.TimeStat, int32(msg.Metrics.CPUTime.Milliseconds()))
+			result.MemoryStat = max(result.MemoryStat, int32(msg.Metrics.Memory))
+		}
+
+		switch {
+		case msg.State == models.Accepted:
+			passed++
+		case msg.State != 0:
+			// tests finish in any order, the solution gets the verdict of the first failed one
+			if result.State == 0 || msg.Test < failedTest {
+				result.State, failedTest = msg.State, msg.Test
+			}
+			if !containsState(states, msg.State) {
+				states = append(states, msg.State)
+			}
+		case msg.Err != nil:
+			internalErr = msg.Err
+		}
+	}
+
+	if internalErr != nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, internalErr, op,
+			fmt.Sprintf("failed to invoke jury solution %q", solution.name))
+	}
+
+	if len(states) == 0 && passed == packet.meta.Count {
+		result.State = models.Accepted
+		states = append(states, models.Accepted)
+	}
+
+	return states, nil
+}
+
+func confirms(expected, got []models.State) bool {
+	if len(got) == 0 {
+		return false
+	}
+
+	// an accepted verdict has to be the only one
+	if containsState(expected, models.Accepted) && len(got) == 1 && got[0] == models.Accepted {
+		return true
+	}
+
+	for _, state := range got {
+		if state != models.Accepted && containsState(expected, state) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsState(states []models.State, state models.State) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+func stateNames(states []models.State) string {
+	if len(states) == 0 {
+		return "no verdict"
+	}
+
+	names := make([]string, len(states))
+	for i, state := range states {
+		names[i] = state.String()
+	}
+	return strings.Join(names, ", ")
+}
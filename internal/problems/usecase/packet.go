@@ -0,0 +1,114 @@
+package usecase
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"github.com/Vyacheslav1557/tester/pkg/tester"
+	"os"
+	"path"
+	"time"
+)
+
+// stageTests puts the uploaded tests into the cache, so that validators and
+// jury solutions can be run on them before the archive goes to S3
+func (u *UseCase) stageTests(id int32, properties *ProblemProperties, tests *bytes.Buffer) (uploadPacket, func(), error) {
+	const op = "UseCase.stageTests"
+
+	name := fmt.Sprintf("%d_upload_%d", id, time.Now().UnixNano())
+	zipPath := path.Join(u.cacheDir, "archives", name+".zip")
+
+	if err := os.WriteFile(zipPath, tests.Bytes(), 0644); err != nil {
+		return uploadPacket{}, nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to write tests archive")
+	}
+
+	packet := uploadPacket{
+		name:        name,
+		zipPath:     zipPath,
+		timeLimit:   properties.TimeLimit,
+		memoryLimit: properties.MemoryLimit,
+		meta:        properties.Meta,
+	}
+
+	cleanup := func() {
+		os.Remove(zipPath)
+		u.tester.RemoveTests(packet)
+	}
+
+	return packet, cleanup, nil
+}
+
+type uploadPacket struct {
+	name        string
+	zipPath     string
+	timeLimit   int64
+	memoryLimit int64
+	meta        *models.Meta
+}
+
+func (p uploadPacket) ContestId() int32 {
+	return 0
+}
+
+func (p uploadPacket) UniquePacketName() string {
+	return p.name
+}
+
+func (p uploadPacket) ZipPath() string {
+	return p.zipPath
+}
+
+func (p uploadPacket) TL() int64 {
+	return p.timeLimit
+}
+
+func (p uploadPacket) ML() int64 {
+	return p.memoryLimit
+}
+
+func (p uploadPacket) Meta() *models.Meta {
+	return p.meta
+}
+
+// Strategy runs every test, the jury report needs all verdicts of a solution
+func (p uploadPacket) Strategy() models.Strategy {
+	return models.StrategyIOI
+}
+
+// Priority puts the jury solutions along with practice, a live contest must not wait for an upload
+func (p uploadPacket) Priority() tester.Priority {
+	return tester.PriorityPractice
+}
+
+// Type is standard, jury solutions are programs even for output-only problems
+func (p uploadPacket) Type() models.ProblemType {
+	return models.ProblemStandard
+}
+
+// program is a jury solution or a validator taken from the package
+type program struct {
+	source       []byte
+	lang         models.LanguageName
+	dependencies map[string][]byte
+}
+
+func (p *program) Id() int32 {
+	return 0
+}
+
+func (p *program) UserId() int32 {
+	return 0
+}
+
+func (p *program) Solution() []byte {
+	return p.source
+}
+
+func (p *program) Lang() models.LanguageName {
+	return p.lang
+}
+
+func (p *program) Dependencies() map[string][]byte {
+	return p.dependencies
+}
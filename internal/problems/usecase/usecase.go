@@ -16,13 +16,21 @@ import (
 
 	"github.com/Vyacheslav1557/tester/internal/models"
 	"github.com/Vyacheslav1557/tester/pkg"
+	"github.com/Vyacheslav1557/tester/pkg/tester"
 	"github.com/microcosm-cc/bluemonday"
 )
 
+type Tester interface {
+	Test(ctx context.Context, packet tester.Packet, s tester.Solution) <-chan tester.TestingMessage
+	Validate(ctx context.Context, packet tester.Packet, validator tester.Solution) ([]tester.ValidationFailure, error)
+	RemoveTests(packet tester.Packet) error
+}
+
 type UseCase struct {
 	problemRepo  problems.Repository
 	pandocClient pkg.PandocClient
 	s3Repo       problems.S3Repository
+	tester       Tester
 	cacheDir     string
 }
 
@@ -30,6 +38,7 @@ func NewUseCase(
 	problemRepo problems.Repository,
 	pandocClient pkg.PandocClient,
 	s3Repo problems.S3Repository,
+	tester Tester,
 	cacheDir string,
 ) *UseCase {
 	err := os.MkdirAll(path.Join(cacheDir, "archives"), 0755)
@@ -41,6 +50,7 @@ func NewUseCase(
 		problemRepo:  problemRepo,
 		pandocClient: pandocClient,
 		s3Repo:       s3Repo,
+		tester:       tester,
 		cacheDir:     cacheDir,
 	}
 }
@@ -76,6 +86,68 @@ func (u *UseCase) DownloadTestsArchive(ctx context.Context, id int32) (string, e
 	return zipPath, nil
 }
 
+func (u *UseCase) DownloadSamplesArchive(ctx context.Context, id int32) (string, error) {
+	const op = "UseCase.DownloadSamplesArchive"
+
+	problem, err := u.problemRepo.GetProblemById(ctx, u.problemRepo.DB(), id)
+	if err != nil {
+		return "", err
+	}
+
+	if len(problem.Samples) == 0 {
+		return "", pkg.Wrap(pkg.ErrBadInput, nil, op, "problem has no sample tests")
+	}
+
+	zipPath := path.Join(u.cacheDir, "archives", fmt.Sprintf("%d_samples.zip", id))
+
+	f, err := os.Create(zipPath)
+	if err != nil {
+		return "", pkg.Wrap(pkg.ErrInternal, err, op, "failed to create samples archive")
+	}
+	defer f.Close()
+
+	meta := problem.Samples.Meta()
+
+	samplesArchive := zip.NewWriter(f)
+	for i, sample := range problem.Samples {
+		name := meta.Names[i]
+		if err := writeZipFile(samplesArchive, "tests/"+name, sample.Input); err != nil {
+			return "", pkg.Wrap(pkg.ErrInternal, err, op, "failed to write sample input")
+		}
+		if err := writeZipFile(samplesArchive, "tests/"+name+".a", sample.Output); err != nil {
+			return "", pkg.Wrap(pkg.ErrInternal, err, op, "failed to write sample output")
+		}
+	}
+
+	// the samples are checked the way the tests are
+	if problem.Meta.Checker != nil {
+		testsPath, err := u.DownloadTestsArchive(ctx, id)
+		if err != nil {
+			return "", err
+		}
+
+		if err := copyChecker(testsPath, samplesArchive); err != nil {
+			return "", err
+		}
+	}
+
+	if err := samplesArchive.Close(); err != nil {
+		return "", pkg.Wrap(pkg.ErrInternal, err, op, "failed to close samples archive")
+	}
+
+	return zipPath, nil
+}
+
+func writeZipFile(w *zip.Writer, name string, content string) error {
+	f, err := w.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(f, content)
+	return err
+}
+
 func (u *UseCase) DeleteProblem(ctx context.Context, id int32) error {
 	return u.problemRepo.DeleteProblem(ctx, u.problemRepo.DB(), id)
 }
@@ -89,6 +161,38 @@ func (u *UseCase) UpdateProblem(ctx context.Context, id int32, problemUpdate *mo
 		return pkg.Wrap(pkg.ErrBadInput, nil, "UpdateProblem", "empty problem update")
 	}
 
+	if problemUpdate.ValidatorPattern != nil {
+		if _, err := compilePattern(*problemUpdate.ValidatorPattern); err != nil {
+			return err
+		}
+	}
+
+	if problemUpdate.Strategy != nil {
+		if err := problemUpdate.Strategy.Valid(); err != nil {
+			return err
+		}
+		if *problemUpdate.Strategy == models.StrategyDefault {
+			return pkg.Wrap(pkg.ErrBadInput, nil, "UpdateProblem", "problem strategy must be set")
+		}
+	}
+
+	if problemUpdate.Type != nil {
+		if err := problemUpdate.Type.Valid(); err != nil {
+			return err
+		}
+	}
+
+	if problemUpdate.Graders != nil {
+		if err := problemUpdate.Graders.Valid(); err != nil {
+			return err
+		}
+		for _, grader := range *problemUpdate.Graders {
+			if err := tester.CheckGrader(grader.Language); err != nil {
+				return err
+			}
+		}
+	}
+
 	tx, err := u.problemRepo.BeginTx(ctx)
 	if err != nil {
 		return err
@@ -159,6 +263,8 @@ type ProblemProperties struct {
 	OutputFormat *string `json:"output"`
 	InputFormat  *string `json:"input"`
 
+	SampleTests []SampleTest `json:"sampleTests"`
+
 	Meta *models.Meta
 
 	//Tutorial    *string      `json:"tutorial"`
@@ -166,22 +272,64 @@ type ProblemProperties struct {
 	//OutputFile  string       `json:"outputFile"`
 	//AuthorName  string       `json:"authorName"`
 	//Language    string       `json:"language"`
-	//SampleTests []SampleTest `json:"sampleTests"`
 	//Interaction *string      `json:"interaction"`
 	//AuthorLogin string       `json:"authorLogin"`
 }
 
+type SampleTest struct {
+	Input  string `json:"input"`
+	Output string `json:"output"`
+}
+
 func (u *UseCase) UploadProblem(ctx context.Context, id int32, r io.ReaderAt, size int64) error {
 	const op = "UseCase.UploadProblem"
 
-	// Initialize zip reader
-	zipReader, err := zip.NewReader(r, size)
+	// Initialize zip reader, it rejects unsafe entries and zip bombs
+	archive, err := tester.NewArchiveReader(r, size, tester.DefaultArchiveLimits)
 	if err != nil {
-		return pkg.Wrap(pkg.ErrBadInput, err, op, "failed to open zip")
+		return err
 	}
 
 	// Process zip contents
-	properties, testsBuffer, err := processZipContents(ctx, zipReader)
+	properties, testsBuffer, err := processZipContents(ctx, archive)
+	if err != nil {
+		return err
+	}
+
+	problem, err := u.problemRepo.GetProblemById(ctx, u.problemRepo.DB(), id)
+	if err != nil {
+		return err
+	}
+
+	validator, err := readValidator(archive)
+	if err != nil {
+		return err
+	}
+
+	solutions, err := readJurySolutions(archive)
+	if err != nil {
+		return err
+	}
+
+	properties.Meta.Groups, err = readTestGroups(archive, properties.Meta)
+	if err != nil {
+		return err
+	}
+
+	packet, cleanup, err := u.stageTests(id, properties, testsBuffer)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	// Broken tests must not get to S3
+	err = u.validateInputs(ctx, packet, testsBuffer, problem.ValidatorPattern, validator)
+	if err != nil {
+		return err
+	}
+
+	// Run jury solutions, the report is stored along with this revision
+	report, err := u.judge(ctx, packet, solutions)
 	if err != nil {
 		return err
 	}
@@ -199,7 +347,9 @@ func (u *UseCase) UploadProblem(ctx context.Context, id int32, r io.ReaderAt, si
 		Notes:        properties.Notes,
 		Scoring:      properties.Scoring,
 
-		Meta: properties.Meta,
+		Meta:       properties.Meta,
+		Samples:    samples(properties.SampleTests),
+		JuryReport: report,
 	}
 
 	if err := u.UpdateProblem(ctx, id, problemUpdate); err != nil {
@@ -214,7 +364,7 @@ func (u *UseCase) UploadProblem(ctx context.Context, id int32, r io.ReaderAt, si
 	return nil
 }
 
-func processZipContents(_ context.Context, zipReader *zip.Reader) (*ProblemProperties, *bytes.Buffer, error) {
+func processZipContents(_ context.Context, archive *tester.ArchiveReader) (*ProblemProperties, *bytes.Buffer, error) {
 	const op = "processZipContents"
 
 	const locale = "russian"
@@ -227,14 +377,17 @@ func processZipContents(_ context.Context, zipReader *zip.Reader) (*ProblemPrope
 	testInputs := make(map[string]bool)
 	testOutputs := make(map[string]bool)
 
-	for _, file := range zipReader.File {
+	for _, file := range archive.Files() {
 		if file.FileInfo().IsDir() || isInvalidTestFile(file.Name) {
 			continue
 		}
 
 		if file.Name == fmt.Sprintf("statements/%s/problem-properties.json", locale) {
 			var err error
-			properties, err = readProperties(file)
+			properties, err = readProperties(archive, file)
+			if errors.Is(err, pkg.ErrBadInput) {
+				return nil, nil, err
+			}
 			if err != nil {
 				return nil, nil, pkg.Wrap(pkg.ErrBadInput, err,
 					op, "failed to read problem-properties.json")
@@ -250,7 +403,10 @@ func processZipContents(_ context.Context, zipReader *zip.Reader) (*ProblemPrope
 				testInputs[fileName] = true
 			}
 
-			if err := copyTestFile(file, testsArchive); err != nil {
+			if err := copyTestFile(archive, file, testsArchive); err != nil {
+				if errors.Is(err, pkg.ErrBadInput) {
+					return nil, nil, err
+				}
 				return nil, nil, pkg.Wrap(pkg.ErrBadInput, err, op, "failed to copy test file")
 			}
 		}
@@ -268,11 +424,18 @@ func processZipContents(_ context.Context, zipReader *zip.Reader) (*ProblemPrope
 	for input := range testInputs {
 		names = append(names, input)
 	}
+	sortTestNames(names)
 	meta.Names = names
 	meta.Count = len(meta.Names)
 	properties.MemoryLimit /= 1024 * 1024 // Convert bytes to MB
 	properties.Meta = &meta
 
+	var err error
+	meta.Checker, err = addChecker(archive, testsArchive)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	if err := testsArchive.Close(); err != nil {
 		return nil, nil, err
 	}
@@ -280,13 +443,24 @@ func processZipContents(_ context.Context, zipReader *zip.Reader) (*ProblemPrope
 	return properties, testsBuffer, nil
 }
 
+func samples(tests []SampleTest) *[]models.Sample {
+	res := make([]models.Sample, len(tests))
+	for i, test := range tests {
+		res[i] = models.Sample{
+			Input:  test.Input,
+			Output: test.Output,
+		}
+	}
+	return &res
+}
+
 func isInvalidTestFile(name string) bool {
 	fileName := filepath.Base(name)
 	return fileName == "" || strings.HasPrefix(fileName, ".")
 }
 
-func copyTestFile(src *zip.File, dst *zip.Writer) error {
-	srcReader, err := src.Open()
+func copyTestFile(archive *tester.ArchiveReader, src *zip.File, dst *zip.Writer) error {
+	srcReader, err := archive.Open(src)
 	if err != nil {
 		return fmt.Errorf("failed to open test file: %w", err)
 	}
@@ -320,8 +494,8 @@ func validateTests(inputs, outputs map[string]bool) error {
 	return nil
 }
 
-func readProperties(f *zip.File) (*ProblemProperties, error) {
-	file, err := f.Open()
+func readProperties(archive *tester.ArchiveReader, f *zip.File) (*ProblemProperties, error) {
+	file, err := archive.Open(f)
 	if err != nil {
 		return nil, err
 	}
@@ -343,7 +517,11 @@ func isEmpty(p models.ProblemUpdate) bool {
 		p.Notes == nil &&
 		p.Scoring == nil &&
 		p.MemoryLimit == nil &&
-		p.TimeLimit == nil
+		p.TimeLimit == nil &&
+		p.ValidatorPattern == nil &&
+		p.Strategy == nil &&
+		p.Type == nil &&
+		p.Graders == nil
 }
 
 func wrap(s string) string {
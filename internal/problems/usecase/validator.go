@@ -0,0 +1,166 @@
+package usecase
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"github.com/Vyacheslav1557/tester/pkg/tester"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// maxReportedFailures bounds the number of tests listed in the rejection message
+const maxReportedFailures = 20
+
+// readValidator takes the first validator declared in problem.xml.
+// Headers lying next to its source, e.g. testlib.h, are compiled along with it.
+func readValidator(archive *tester.ArchiveReader) (*program, error) {
+	problem, files, err := readDescriptor(archive)
+	if err != nil || problem == nil || len(problem.Validators) == 0 {
+		return nil, err
+	}
+
+	return readProgram(archive, files, problem.Validators[0].Source, "validator")
+}
+
+// readProgram reads a jury program of the package along with the headers lying next to its source
+func readProgram(archive *tester.ArchiveReader, files map[string]*zip.File, source sourceXML, what string) (*program, error) {
+	const op = "readProgram"
+
+	lang, ok := juryLanguage(source.Type)
+	if !ok {
+		return nil, pkg.Wrap(pkg.ErrBadInput, nil, op,
+			fmt.Sprintf("%s language %q is not supported", what, source.Type))
+	}
+
+	file, ok := files[source.Path]
+	if !ok {
+		return nil, pkg.Wrap(pkg.ErrBadInput, nil, op, fmt.Sprintf("%s %q not found", what, source.Path))
+	}
+
+	p := &program{
+		lang:         lang,
+		dependencies: make(map[string][]byte),
+	}
+
+	var err error
+	p.source, err = readArchiveFile(archive, file)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := path.Dir(source.Path)
+	for name, file := range files {
+		if path.Dir(name) != dir || path.Ext(name) != ".h" || file.FileInfo().IsDir() {
+			continue
+		}
+
+		p.dependencies[path.Base(name)], err = readArchiveFile(archive, file)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// compilePattern anchors the validator pattern, so that it has to match the whole input
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	const op = "compilePattern"
+
+	re, err := regexp.Compile(`\A(?:` + pattern + `)\z`)
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrBadInput, err, op, "invalid validator pattern")
+	}
+
+	return re, nil
+}
+
+// matchInputs streams every test input of the tests archive through re
+func matchInputs(tests *bytes.Buffer, re *regexp.Regexp) ([]tester.ValidationFailure, error) {
+	const op = "matchInputs"
+
+	archive, err := zip.NewReader(bytes.NewReader(tests.Bytes()), int64(tests.Len()))
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to open tests archive")
+	}
+
+	var failures []tester.ValidationFailure
+	for _, file := range archive.File {
+		if !strings.HasPrefix(file.Name, "tests/") || strings.HasSuffix(file.Name, ".a") {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to open test")
+		}
+
+		matched := re.MatchReader(bufio.NewReader(rc))
+		rc.Close()
+
+		if !matched {
+			failures = append(failures, tester.ValidationFailure{
+				Test:    path.Base(file.Name),
+				Message: "input does not match validator pattern",
+			})
+		}
+	}
+
+	return failures, nil
+}
+
+// validateInputs checks every test input against the problem's pattern and
+// the package validator, the upload is rejected if any of them fails
+func (u *UseCase) validateInputs(
+	ctx context.Context,
+	packet uploadPacket,
+	tests *bytes.Buffer,
+	pattern string,
+	validator *program,
+) error {
+	const op = "UseCase.validateInputs"
+
+	var failures []tester.ValidationFailure
+
+	if pattern != "" {
+		re, err := compilePattern(pattern)
+		if err != nil {
+			return err
+		}
+
+		rejected, err := matchInputs(tests, re)
+		if err != nil {
+			return err
+		}
+		failures = append(failures, rejected...)
+	}
+
+	if validator != nil {
+		rejected, err := u.tester.Validate(ctx, packet, validator)
+		if err != nil {
+			return err
+		}
+		failures = append(failures, rejected...)
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+
+	var msg strings.Builder
+	msg.WriteString("input validation failed")
+	for i, failure := range failures {
+		if i == maxReportedFailures {
+			fmt.Fprintf(&msg, "\n... and %d more", len(failures)-i)
+			break
+		}
+		fmt.Fprintf(&msg, "\ntest %s: %s", failure.Test, failure.Message)
+	}
+
+	return pkg.Wrap(pkg.ErrBadInput, nil, op, msg.String())
+}
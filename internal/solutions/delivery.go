@@ -8,7 +8,13 @@ import (
 type SolutionsHandlers interface {
 	CreateSolution(c *fiber.Ctx, params testerv1.CreateSolutionParams) error
 	GetSolution(c *fiber.Ctx, id int32) error
+	GetSolutionArchive(c *fiber.Ctx, id int32) error
+	GetSolutionDetails(c *fiber.Ctx, id int32) error
+	DiffSolutions(c *fiber.Ctx, params testerv1.DiffSolutionsParams) error
 	ListSolutions(c *fiber.Ctx, params testerv1.ListSolutionsParams) error
+	RunSolution(c *fiber.Ctx, params testerv1.RunSolutionParams) error
+	RejudgeSolutions(c *fiber.Ctx) error
+	GetJudgeQueues(c *fiber.Ctx) error
 
 	//ListSolutionsWS(c *websocket.Conn)
 	//ListSolutionsMiddleware(c *fiber.Ctx) error
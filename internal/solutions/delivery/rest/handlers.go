@@ -2,6 +2,7 @@ package rest
 
 import (
 	"context"
+	"fmt"
 	testerv1 "github.com/Vyacheslav1557/tester/contracts/tester/v1"
 	"github.com/Vyacheslav1557/tester/internal/contests"
 	"github.com/Vyacheslav1557/tester/internal/models"
@@ -11,6 +12,8 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v4"
 	"io"
+	"path/filepath"
+	"strings"
 	"unicode/utf8"
 )
 
@@ -36,7 +39,11 @@ func NewHandlers(
 
 const (
 	maxSolutionSize int64 = 10 * 1024 * 1024 // 10 MB
+	maxRunInputSize int64 = 1024 * 1024      // 1 MB
 	sessionKey            = "session"
+
+	modeFull    = "full"
+	modeSamples = "samples"
 )
 
 func sessionFromCtx(ctx context.Context) (*models.Session, error) {
@@ -78,55 +85,222 @@ func (h *Handlers) CreateSolution(c *fiber.Ctx, params testerv1.CreateSolutionPa
 		return pkg.NoPermission
 	}
 
+	solution, archive, err := readSubmission(c)
+	if err != nil {
+		return err
+	}
+
+	// check if language is valid
+	langName := models.LanguageName(params.Language)
+	if err := langName.Valid(); err != nil {
+		return err
+	}
+
+	creation := &models.SolutionCreation{
+		UserId:    session.UserId,
+		ProblemId: params.ProblemId,
+		ContestId: params.ContestId,
+		Language:  langName,
+		Solution:  solution,
+		Archive:   archive,
+		Penalty:   20, // TODO: get penalty from contest
+		Unlimited: session.Role == models.RoleAdmin || session.Role == models.RoleTeacher,
+	}
+
+	if params.Mode != nil {
+		switch string(*params.Mode) {
+		case modeSamples:
+			// samples-only runs are not counted in the monitor and carry no penalty
+			creation.Pretest = true
+			creation.Penalty = 0
+		case modeFull:
+			break
+		default:
+			return pkg.Wrap(pkg.ErrBadInput, nil, op, "invalid mode")
+		}
+	}
+
+	id, err := h.solutionsUC.CreateSolution(ctx, creation)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(testerv1.CreationResponse{Id: id})
+}
+
+// readSubmission reads the solution of CreateSolution,
+// a .zip file is a multi-file solution and is returned as is
+func readSubmission(c *fiber.Ctx) (string, []byte, error) {
+	const op = "readSubmission"
+
 	s, err := c.FormFile("solution")
 	if err != nil {
-		return pkg.Wrap(pkg.ErrBadInput, err, op, "failed to get solution")
+		return "", nil, pkg.Wrap(pkg.ErrBadInput, err, op, "failed to get solution")
+	}
+
+	if !strings.EqualFold(filepath.Ext(s.Filename), ".zip") {
+		solution, err := readSolution(c)
+		return solution, nil, err
 	}
 
 	if s.Size == 0 || s.Size > maxSolutionSize {
-		return pkg.Wrap(pkg.ErrBadInput, err, op, "invalid solution size")
+		return "", nil, pkg.Wrap(pkg.ErrBadInput, err, op, "invalid archive size")
 	}
 
 	f, err := s.Open()
 	if err != nil {
-		return pkg.Wrap(pkg.ErrBadInput, err, op, "failed to open solution")
+		return "", nil, pkg.Wrap(pkg.ErrBadInput, err, op, "failed to open archive")
 	}
 	defer f.Close()
 
 	b, err := io.ReadAll(f)
 	if err != nil {
-		return pkg.Wrap(pkg.ErrBadInput, err, op, "failed to read solution")
+		return "", nil, pkg.Wrap(pkg.ErrBadInput, err, op, "failed to read archive")
+	}
+
+	return "", b, nil
+}
+
+func readSolution(c *fiber.Ctx) (string, error) {
+	const op = "readSolution"
+
+	s, err := c.FormFile("solution")
+	if err != nil {
+		return "", pkg.Wrap(pkg.ErrBadInput, err, op, "failed to get solution")
+	}
+
+	if s.Size == 0 || s.Size > maxSolutionSize {
+		return "", pkg.Wrap(pkg.ErrBadInput, err, op, "invalid solution size")
+	}
+
+	f, err := s.Open()
+	if err != nil {
+		return "", pkg.Wrap(pkg.ErrBadInput, err, op, "failed to open solution")
+	}
+	defer f.Close()
+
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return "", pkg.Wrap(pkg.ErrBadInput, err, op, "failed to read solution")
 	}
 
 	if len(b) == 0 {
-		return pkg.Wrap(pkg.ErrBadInput, err, op, "solution is empty")
+		return "", pkg.Wrap(pkg.ErrBadInput, err, op, "solution is empty")
 	}
 
 	solution := string(b)
 
 	if !utf8.ValidString(solution) {
-		return pkg.Wrap(pkg.ErrBadInput, err, op, "solution is not valid utf-8")
+		return "", pkg.Wrap(pkg.ErrBadInput, err, op, "solution is not valid utf-8")
+	}
+
+	return solution, nil
+}
+
+func (h *Handlers) RunSolution(c *fiber.Ctx, params testerv1.RunSolutionParams) error {
+	const op = "SolutionsHandlers.RunSolution"
+
+	ctx := c.Context()
+
+	session, err := sessionFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	// the limits and the languages of a problem are those of its contest
+	if params.ProblemId != nil && params.ContestId == nil {
+		return pkg.Wrap(pkg.ErrBadInput, nil, op, "contest id is required")
+	}
+
+	switch session.Role {
+	case models.RoleAdmin, models.RoleTeacher:
+		break
+	case models.RoleStudent:
+		// students may use the limits of a problem only from a contest they participate in
+		if params.ProblemId != nil {
+			isParticipant, err := h.contestsUC.IsParticipant(ctx, *params.ContestId, session.UserId)
+			if err != nil {
+				return err
+			}
+
+			if !isParticipant {
+				return pkg.NoPermission
+			}
+		}
+
+		break
+	default:
+		return pkg.NoPermission
+	}
+
+	solution, err := readSolution(c)
+	if err != nil {
+		return err
+	}
+
+	input, err := readInput(c)
+	if err != nil {
+		return err
 	}
 
-	// check if language is valid
 	langName := models.LanguageName(params.Language)
 	if err := langName.Valid(); err != nil {
 		return err
 	}
 
-	id, err := h.solutionsUC.CreateSolution(ctx, &models.SolutionCreation{
+	res, err := h.solutionsUC.RunSolution(ctx, &models.SolutionRun{
 		UserId:    session.UserId,
-		ProblemId: params.ProblemId,
 		ContestId: params.ContestId,
+		ProblemId: params.ProblemId,
 		Language:  langName,
 		Solution:  solution,
-		Penalty:   20, // TODO: get penalty from contest
+		Input:     input,
 	})
 	if err != nil {
 		return err
 	}
 
-	return c.JSON(testerv1.CreationResponse{Id: id})
+	return c.JSON(testerv1.RunSolutionResponse{
+		State:      int32(res.State),
+		Stdout:     res.Stdout,
+		Stderr:     res.Stderr,
+		ExitCode:   res.ExitCode,
+		TimeStat:   res.TimeStat,
+		MemoryStat: res.MemoryStat,
+	})
+}
+
+// readInput reads the optional stdin of a custom invocation
+func readInput(c *fiber.Ctx) (string, error) {
+	const op = "readInput"
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return "", pkg.Wrap(pkg.ErrBadInput, err, op, "failed to parse form")
+	}
+
+	files := form.File["input"]
+	if len(files) == 0 {
+		return "", nil
+	}
+	in := files[0]
+
+	if in.Size > maxRunInputSize {
+		return "", pkg.Wrap(pkg.ErrBadInput, nil, op, "invalid input size")
+	}
+
+	f, err := in.Open()
+	if err != nil {
+		return "", pkg.Wrap(pkg.ErrBadInput, err, op, "failed to open input")
+	}
+	defer f.Close()
+
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return "", pkg.Wrap(pkg.ErrBadInput, err, op, "failed to read input")
+	}
+
+	return string(b), nil
 }
 
 func (h *Handlers) GetSolution(c *fiber.Ctx, id int32) error {
@@ -170,6 +344,179 @@ func (h *Handlers) GetSolution(c *fiber.Ctx, id int32) error {
 	}
 }
 
+// GetSolutionArchive sends the zip of a multi-file solution
+func (h *Handlers) GetSolutionArchive(c *fiber.Ctx, id int32) error {
+	ctx := c.Context()
+
+	session, err := sessionFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	solution, err := h.solutionsUC.GetSolution(ctx, id)
+
+	switch session.Role {
+	case models.RoleAdmin, models.RoleTeacher:
+		break
+	case models.RoleStudent:
+		// check if the solution belongs to the user
+		if err == nil && solution.UserId != session.UserId {
+			return pkg.NoPermission
+		}
+	default:
+		return pkg.NoPermission
+	}
+
+	if err != nil {
+		return err
+	}
+
+	archive, err := h.solutionsUC.DownloadArchive(ctx, solution)
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="solution-%d.zip"`, id))
+
+	return c.Send(archive)
+}
+
+// GetSolutionDetails shows the judge diagnostics of a solution. The author
+// and the teachers only get the compile log, the runtime details are for admins.
+func (h *Handlers) GetSolutionDetails(c *fiber.Ctx, id int32) error {
+	ctx := c.Context()
+
+	session, err := sessionFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	solution, err := h.solutionsUC.GetSolution(ctx, id)
+
+	switch session.Role {
+	case models.RoleAdmin:
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(testerv1.GetSolutionDetailsResponse{Details: SolutionDetailsDTO(solution.Diagnostics, true)})
+	case models.RoleTeacher, models.RoleStudent:
+		// check if the solution belongs to the user
+		if err == nil && session.Role == models.RoleStudent && solution.UserId != session.UserId {
+			return pkg.NoPermission
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(testerv1.GetSolutionDetailsResponse{Details: SolutionDetailsDTO(solution.Diagnostics, false)})
+	default:
+		return pkg.NoPermission
+	}
+}
+
+// DiffSolutions shows what has changed between two attempts,
+// students may only compare their own solutions
+func (h *Handlers) DiffSolutions(c *fiber.Ctx, params testerv1.DiffSolutionsParams) error {
+	ctx := c.Context()
+
+	session, err := sessionFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	ignoreWhitespace := params.IgnoreWhitespace != nil && *params.IgnoreWhitespace
+
+	switch session.Role {
+	case models.RoleAdmin, models.RoleTeacher:
+		d, err := h.solutionsUC.DiffSolutions(ctx, params.From, params.To, ignoreWhitespace)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(DiffSolutionsResponseDTO(d))
+	case models.RoleStudent:
+		d, err := h.solutionsUC.DiffSolutions(ctx, params.From, params.To, ignoreWhitespace)
+		if err != nil {
+			return err
+		}
+
+		// check if both solutions belong to the user
+		if d.From.UserId != session.UserId || d.To.UserId != session.UserId {
+			return pkg.NoPermission
+		}
+
+		return c.JSON(DiffSolutionsResponseDTO(d))
+	default:
+		return pkg.NoPermission
+	}
+}
+
+// RejudgeSolutions tests the given solutions once again, e.g. after the judge has failed on them
+func (h *Handlers) RejudgeSolutions(c *fiber.Ctx) error {
+	const op = "SolutionsHandlers.RejudgeSolutions"
+
+	ctx := c.Context()
+
+	session, err := sessionFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch session.Role {
+	case models.RoleAdmin:
+		var req testerv1.RejudgeSolutionsRequest
+		err := c.BodyParser(&req)
+		if err != nil {
+			return pkg.Wrap(pkg.ErrBadInput, err, op, "failed to parse request")
+		}
+
+		if len(req.SolutionIds) == 0 {
+			return pkg.Wrap(pkg.ErrBadInput, nil, op, "no solutions to rejudge")
+		}
+
+		err = h.solutionsUC.RejudgeSolutions(ctx, req.SolutionIds)
+		if err != nil {
+			return err
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	default:
+		return pkg.NoPermission
+	}
+}
+
+// GetJudgeQueues shows how busy the judge is per priority
+func (h *Handlers) GetJudgeQueues(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	session, err := sessionFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch session.Role {
+	case models.RoleAdmin:
+		queues, err := h.solutionsUC.JudgeQueues(ctx)
+		if err != nil {
+			return err
+		}
+
+		resp := testerv1.GetJudgeQueuesResponse{
+			Queues: make([]testerv1.JudgeQueue, len(queues)),
+		}
+		for i, q := range queues {
+			resp.Queues[i] = JudgeQueueDTO(*q)
+		}
+
+		return c.JSON(resp)
+	default:
+		return pkg.NoPermission
+	}
+}
+
 func (h *Handlers) ListSolutions(c *fiber.Ctx, params testerv1.ListSolutionsParams) error {
 	ctx := c.Context()
 
@@ -184,7 +531,8 @@ func (h *Handlers) ListSolutions(c *fiber.Ctx, params testerv1.ListSolutionsPara
 
 	switch session.Role {
 	case models.RoleAdmin, models.RoleTeacher:
-		if params.ContestId == nil {
+		// admins may list all contests at once, e.g. to find the failed judgements
+		if params.ContestId == nil && session.Role != models.RoleAdmin {
 			return pkg.Wrap(pkg.ErrBadInput, nil, "", "contest id is required")
 		}
 
@@ -193,7 +541,12 @@ func (h *Handlers) ListSolutions(c *fiber.Ctx, params testerv1.ListSolutionsPara
 			return err
 		}
 
-		at, err := NewJWT(session.UserId, *params.ContestId, session.Role)
+		var contestId int32
+		if params.ContestId != nil {
+			contestId = *params.ContestId
+		}
+
+		at, err := NewJWT(session.UserId, contestId, session.Role)
 		if err != nil {
 			return err
 		}
@@ -299,6 +652,8 @@ func SolutionsListItemDTO(s models.SolutionsListItem) testerv1.SolutionsListItem
 		TimeStat:   s.TimeStat,
 		MemoryStat: s.MemoryStat,
 		Language:   int32(s.Language),
+		Pretest:    s.Pretest,
+		FailedTest: s.FailedTest,
 
 		ProblemId:    s.ProblemId,
 		ProblemTitle: s.ProblemTitle,
@@ -321,6 +676,7 @@ func SolutionDTO(s models.Solution) testerv1.Solution {
 		Username: s.Username,
 
 		Solution: s.Solution,
+		Archive:  s.ArchiveKey != nil,
 
 		State:      int32(s.State),
 		Score:      s.Score,
@@ -328,6 +684,8 @@ func SolutionDTO(s models.Solution) testerv1.Solution {
 		TimeStat:   s.TimeStat,
 		MemoryStat: s.MemoryStat,
 		Language:   int32(s.Language),
+		Pretest:    s.Pretest,
+		FailedTest: s.FailedTest,
 
 		ProblemId:    s.ProblemId,
 		ProblemTitle: s.ProblemTitle,
@@ -341,3 +699,36 @@ func SolutionDTO(s models.Solution) testerv1.Solution {
 		UpdatedAt: s.UpdatedAt,
 	}
 }
+
+// SolutionDetailsDTO leaves the runtime details out unless full is set
+func SolutionDetailsDTO(d models.Diagnostics, full bool) testerv1.SolutionDetails {
+	details := testerv1.SolutionDetails{
+		CompileLog: d.CompileLog,
+	}
+
+	if full {
+		details.Stderr = &d.Stderr
+		details.ExitCode = &d.ExitCode
+		details.Signal = &d.Signal
+		details.Error = &d.Error
+	}
+
+	return details
+}
+
+func JudgeQueueDTO(q models.JudgeQueue) testerv1.JudgeQueue {
+	return testerv1.JudgeQueue{
+		Priority:    q.Priority,
+		Depth:       q.Depth,
+		OldestWait:  q.OldestWait.Milliseconds(),
+		AverageWait: q.AverageWait.Milliseconds(),
+	}
+}
+
+func DiffSolutionsResponseDTO(d *models.SolutionsDiff) testerv1.DiffSolutionsResponse {
+	return testerv1.DiffSolutionsResponse{
+		From: d.From.Id,
+		To:   d.To.Id,
+		Diff: d.Diff,
+	}
+}
@@ -3,11 +3,35 @@ package solutions
 import (
 	"context"
 	"github.com/Vyacheslav1557/tester/internal/models"
+	"io"
+	"time"
 )
 
 type Repository interface {
 	GetSolution(ctx context.Context, id int32) (*models.Solution, error)
 	CreateSolution(ctx context.Context, creation *models.SolutionCreation) (int32, error)
+	DeleteSolution(ctx context.Context, id int32) error
 	UpdateSolution(ctx context.Context, id int32, update *models.SolutionUpdate) error
 	ListSolutions(ctx context.Context, filter models.SolutionsFilter) (*models.SolutionsList, error)
+	FindJudgedDuplicate(ctx context.Context, creation *models.SolutionCreation) (int32, error)
+	SetArchiveKey(ctx context.Context, id int32, key string) error
+	SetSourceKey(ctx context.Context, id int32, key string) error
+	ListLegacySources(ctx context.Context, limit int32) ([]*models.LegacySource, error)
+	MoveSource(ctx context.Context, id int32, key string) error
+}
+
+type ArchivesRepository interface {
+	UploadArchive(ctx context.Context, id int32, reader io.Reader) (string, error)
+	DownloadArchive(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// SolutionStorage keeps the sources of solutions, the database has only their hash, size and key
+type SolutionStorage interface {
+	UploadSource(ctx context.Context, id int32, reader io.Reader) (string, error)
+	DownloadSource(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+type LimitsRepository interface {
+	CountSubmission(ctx context.Context, contestId, userId int32, window time.Duration) (int64, error)
+	UncountSubmission(ctx context.Context, contestId, userId int32) error
 }
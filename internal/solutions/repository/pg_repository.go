@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	sq "github.com/Masterminds/squirrel"
 	"github.com/Vyacheslav1557/tester/internal/models"
 	"github.com/Vyacheslav1557/tester/pkg"
@@ -25,7 +27,12 @@ SELECT s.id,
        s.user_id,
        u.username,
 
-       s.solution,
+       COALESCE(s.solution, '') solution,
+       s.archive_key,
+
+       s.source_key,
+       s.source_hash,
+       s.source_size,
 
        s.state,
        s.score,
@@ -33,6 +40,10 @@ SELECT s.id,
        s.time_stat,
        s.memory_stat,
        s.language,
+       s.pretest,
+       s.failed_test,
+
+       s.diagnostics,
 
        s.problem_id,
        p.title problem_title,
@@ -66,8 +77,8 @@ func (r *PgRepository) GetSolution(ctx context.Context, id int32) (*models.Solut
 
 const (
 	CreateSolutionQuery = `
-INSERT INTO solutions (contest_id, problem_id, user_id, solution, language, penalty) 
-VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+INSERT INTO solutions (contest_id, problem_id, user_id, source_hash, source_size, language, penalty, pretest) 
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
 )
 
 func (r *PgRepository) CreateSolution(ctx context.Context, creation *models.SolutionCreation) (int32, error) {
@@ -78,9 +89,11 @@ func (r *PgRepository) CreateSolution(ctx context.Context, creation *models.Solu
 		creation.ContestId,
 		creation.ProblemId,
 		creation.UserId,
-		creation.Solution,
+		creation.SourceHash,
+		creation.SourceSize,
 		creation.Language,
 		creation.Penalty,
+		creation.Pretest,
 	)
 	if err != nil {
 		return 0, pkg.HandlePgErr(err, op)
@@ -97,15 +110,125 @@ func (r *PgRepository) CreateSolution(ctx context.Context, creation *models.Solu
 	return id, nil
 }
 
+const DeleteSolutionQuery = "DELETE FROM solutions WHERE id = $1"
+
+// DeleteSolution drops a solution which could not be stored completely
+func (r *PgRepository) DeleteSolution(ctx context.Context, id int32) error {
+	const op = "Repository.DeleteSolution"
+
+	_, err := r.db.ExecContext(ctx, DeleteSolutionQuery, id)
+	if err != nil {
+		return pkg.HandlePgErr(err, op)
+	}
+
+	return nil
+}
+
+const SetArchiveKeyQuery = "UPDATE solutions SET archive_key = $1 WHERE id = $2"
+
+// SetArchiveKey links the solution to its zip in the object storage
+func (r *PgRepository) SetArchiveKey(ctx context.Context, id int32, key string) error {
+	const op = "Repository.SetArchiveKey"
+
+	_, err := r.db.ExecContext(ctx, SetArchiveKeyQuery, key, id)
+	if err != nil {
+		return pkg.HandlePgErr(err, op)
+	}
+
+	return nil
+}
+
+const SetSourceKeyQuery = "UPDATE solutions SET source_key = $1 WHERE id = $2"
+
+// SetSourceKey links the solution to its source in the object storage
+func (r *PgRepository) SetSourceKey(ctx context.Context, id int32, key string) error {
+	const op = "Repository.SetSourceKey"
+
+	_, err := r.db.ExecContext(ctx, SetSourceKeyQuery, key, id)
+	if err != nil {
+		return pkg.HandlePgErr(err, op)
+	}
+
+	return nil
+}
+
+const ListLegacySourcesQuery = `
+SELECT id, solution
+FROM solutions
+WHERE source_key IS NULL AND solution IS NOT NULL
+ORDER BY id
+LIMIT $1`
+
+// ListLegacySources returns the sources still kept in the database, the oldest first
+func (r *PgRepository) ListLegacySources(ctx context.Context, limit int32) ([]*models.LegacySource, error) {
+	const op = "Repository.ListLegacySources"
+
+	sources := make([]*models.LegacySource, 0)
+	err := r.db.SelectContext(ctx, &sources, ListLegacySourcesQuery, limit)
+	if err != nil {
+		return nil, pkg.HandlePgErr(err, op)
+	}
+
+	return sources, nil
+}
+
+const MoveSourceQuery = "UPDATE solutions SET source_key = $1, solution = NULL WHERE id = $2 AND source_key IS NULL"
+
+// MoveSource drops the source kept in the database once it is in the object storage
+func (r *PgRepository) MoveSource(ctx context.Context, id int32, key string) error {
+	const op = "Repository.MoveSource"
+
+	_, err := r.db.ExecContext(ctx, MoveSourceQuery, key, id)
+	if err != nil {
+		return pkg.HandlePgErr(err, op)
+	}
+
+	return nil
+}
+
+// verdicts start with 101, solutions still being judged or failed by the judge are not duplicates
+const FindJudgedDuplicateQuery = `
+SELECT id
+FROM solutions
+WHERE contest_id = $1 AND problem_id = $2 AND user_id = $3 AND language = $4 AND pretest = $5
+  AND state > 100 AND source_hash = $6
+ORDER BY id DESC
+LIMIT 1`
+
+// FindJudgedDuplicate returns the id of the same solution of the user
+// which has already got a verdict, zero if there is none
+func (r *PgRepository) FindJudgedDuplicate(ctx context.Context, creation *models.SolutionCreation) (int32, error) {
+	const op = "Repository.FindJudgedDuplicate"
+
+	var id int32
+	err := r.db.GetContext(ctx, &id, FindJudgedDuplicateQuery,
+		creation.ContestId,
+		creation.ProblemId,
+		creation.UserId,
+		creation.Language,
+		creation.Pretest,
+		creation.SourceHash,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, pkg.HandlePgErr(err, op)
+	}
+
+	return id, nil
+}
+
 const UpdateSolutionQuery = `	
 UPDATE solutions
-SET state = $1, score = $2, time_stat = $3, memory_stat = $4
-WHERE id = $5`
+SET state = $1, score = $2, time_stat = $3, memory_stat = $4, failed_test = $5, diagnostics = $6
+WHERE id = $7`
 
 func (r *PgRepository) UpdateSolution(ctx context.Context, id int32, update *models.SolutionUpdate) error {
 	const op = "Repository.UpdateSolution"
 
-	_, err := r.db.ExecContext(ctx, UpdateSolutionQuery, update.State, update.Score, update.TimeStat, update.MemoryStat, id)
+	_, err := r.db.ExecContext(ctx, UpdateSolutionQuery,
+		update.State, update.Score, update.TimeStat, update.MemoryStat, update.FailedTest, update.Diagnostics, id)
 	if err != nil {
 		return pkg.HandlePgErr(err, op)
 	}
@@ -126,6 +249,8 @@ func buildListSolutionsQueries(filter models.SolutionsFilter) (sq.SelectBuilder,
 		"s.time_stat",
 		"s.memory_stat",
 		"s.language",
+		"s.pretest",
+		"s.failed_test",
 
 		"s.problem_id",
 		"p.title problem_title",
@@ -0,0 +1,126 @@
+package repository_test
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/Vyacheslav1557/tester/internal/solutions/repository"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// setupTestDB creates a mocked sqlx.DB and sqlmock instance for runner.
+func setupTestDB(t *testing.T) (*sqlx.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	return sqlxDB, mock
+}
+
+func TestRepository_DeleteSolution(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewRepository(db)
+
+	t.Run("success", func(t *testing.T) {
+		ctx := context.Background()
+
+		mock.ExpectExec(repository.DeleteSolutionQuery).
+			WithArgs(int32(7)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.DeleteSolution(ctx, 7)
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRepository_SetSourceKey(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewRepository(db)
+
+	t.Run("success", func(t *testing.T) {
+		ctx := context.Background()
+
+		mock.ExpectExec(repository.SetSourceKeyQuery).
+			WithArgs("solutions/7/source", int32(7)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.SetSourceKey(ctx, 7, "solutions/7/source")
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		ctx := context.Background()
+
+		mock.ExpectExec(repository.SetSourceKeyQuery).
+			WithArgs("solutions/7/source", int32(7)).
+			WillReturnError(sqlmock.ErrCancelled)
+
+		err := repo.SetSourceKey(ctx, 7, "solutions/7/source")
+		assert.ErrorIs(t, err, pkg.ErrUnhandled)
+	})
+}
+
+func TestRepository_ListLegacySources(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewRepository(db)
+
+	t.Run("success", func(t *testing.T) {
+		ctx := context.Background()
+
+		expected := []*models.LegacySource{
+			{Id: 1, Solution: "int main() {}"},
+			{Id: 2, Solution: "print(1)"},
+		}
+
+		mock.ExpectQuery(repository.ListLegacySourcesQuery).
+			WithArgs(int32(100)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "solution"}).
+				AddRow(expected[0].Id, expected[0].Solution).
+				AddRow(expected[1].Id, expected[1].Solution))
+
+		sources, err := repo.ListLegacySources(ctx, 100)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, sources)
+	})
+
+	t.Run("none", func(t *testing.T) {
+		ctx := context.Background()
+
+		mock.ExpectQuery(repository.ListLegacySourcesQuery).
+			WithArgs(int32(100)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "solution"}))
+
+		sources, err := repo.ListLegacySources(ctx, 100)
+		assert.NoError(t, err)
+		assert.Empty(t, sources)
+	})
+}
+
+func TestRepository_MoveSource(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	repo := repository.NewRepository(db)
+
+	t.Run("success", func(t *testing.T) {
+		ctx := context.Background()
+
+		mock.ExpectExec(repository.MoveSourceQuery).
+			WithArgs("solutions/1/source", int32(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.MoveSource(ctx, 1, "solutions/1/source")
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
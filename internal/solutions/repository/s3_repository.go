@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"io"
+)
+
+// S3Repository keeps the sources of solutions and the zips of multi-file ones
+type S3Repository struct {
+	s3Client *s3.Client
+	bucket   string
+}
+
+func NewS3Repository(s3Client *s3.Client, bucket string) *S3Repository {
+	return &S3Repository{
+		s3Client: s3Client,
+		bucket:   bucket,
+	}
+}
+
+func (r *S3Repository) UploadArchive(ctx context.Context, id int32, reader io.Reader) (string, error) {
+	const op = "S3Repository.UploadArchive"
+
+	key := fmt.Sprintf("solutions/%d/solution.zip", id)
+
+	// archives of solutions are small enough to be uploaded at once
+	_, err := r.s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(r.bucket),
+		Key:    aws.String(key),
+		Body:   reader,
+	})
+	if err != nil {
+		return "", pkg.Wrap(pkg.ErrInternal, err, op, "failed to put object")
+	}
+
+	return key, nil
+}
+
+func (r *S3Repository) DownloadArchive(ctx context.Context, key string) (io.ReadCloser, error) {
+	const op = "S3Repository.DownloadArchive"
+
+	resp, err := r.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(r.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to get object")
+	}
+
+	return resp.Body, nil
+}
+
+func (r *S3Repository) UploadSource(ctx context.Context, id int32, reader io.Reader) (string, error) {
+	const op = "S3Repository.UploadSource"
+
+	key := fmt.Sprintf("solutions/%d/source", id)
+
+	_, err := r.s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(r.bucket),
+		Key:    aws.String(key),
+		Body:   reader,
+	})
+	if err != nil {
+		return "", pkg.Wrap(pkg.ErrInternal, err, op, "failed to put object")
+	}
+
+	return key, nil
+}
+
+func (r *S3Repository) DownloadSource(ctx context.Context, key string) (io.ReadCloser, error) {
+	const op = "S3Repository.DownloadSource"
+
+	resp, err := r.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(r.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to get object")
+	}
+
+	return resp.Body, nil
+}
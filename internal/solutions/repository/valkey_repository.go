@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"github.com/valkey-io/valkey-go"
+	"strconv"
+	"time"
+)
+
+type ValkeyRepository struct {
+	db valkey.Client
+}
+
+func NewValkeyRepository(db valkey.Client) *ValkeyRepository {
+	return &ValkeyRepository{
+		db: db,
+	}
+}
+
+func submissionsKey(contestId, userId int32) string {
+	return fmt.Sprintf("submissions:contest:%d:user:%d", contestId, userId)
+}
+
+// the window starts with the first submission and the counter expires with it
+const countSubmissionScript = `local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return count`
+
+// CountSubmission counts a submission of the user to the contest and returns
+// how many there have been in the current window, this one included
+func (r *ValkeyRepository) CountSubmission(ctx context.Context, contestId, userId int32, window time.Duration) (int64, error) {
+	const op = "ValkeyRepository.CountSubmission"
+
+	resp := valkey.NewLuaScript(countSubmissionScript).Exec(
+		ctx,
+		r.db,
+		[]string{submissionsKey(contestId, userId)},
+		[]string{strconv.FormatInt(window.Milliseconds(), 10)},
+	)
+
+	count, err := resp.AsInt64()
+	if err != nil {
+		if valkey.IsValkeyNil(err) {
+			return 0, pkg.Wrap(pkg.ErrInternal, err, op, "nil response")
+		}
+		return 0, pkg.Wrap(pkg.ErrUnhandled, err, op, "unhandled valkey error")
+	}
+
+	return count, nil
+}
+
+// an expired window is not brought back without its expiration
+const uncountSubmissionScript = `if redis.call('EXISTS', KEYS[1]) == 1 then
+	return redis.call('DECR', KEYS[1])
+end
+return 0`
+
+// UncountSubmission takes back a submission counted by CountSubmission
+// which has not been stored in the end
+func (r *ValkeyRepository) UncountSubmission(ctx context.Context, contestId, userId int32) error {
+	const op = "ValkeyRepository.UncountSubmission"
+
+	resp := valkey.NewLuaScript(uncountSubmissionScript).Exec(
+		ctx,
+		r.db,
+		[]string{submissionsKey(contestId, userId)},
+		[]string{},
+	)
+
+	err := resp.Error()
+	if err != nil {
+		return pkg.Wrap(pkg.ErrUnhandled, err, op, "unhandled valkey error")
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package repository_test
+
+import (
+	"context"
+	"github.com/Vyacheslav1557/tester/internal/solutions/repository"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"github.com/stretchr/testify/require"
+	"github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestValkeyRepository_CountSubmission(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := mock.NewClient(ctrl)
+	limitsRepo := repository.NewValkeyRepository(client)
+
+	matcher := mock.MatchFn(func(cmd []string) bool {
+		if cmd[0] != "EVALSHA" {
+			return false
+		}
+		if cmd[2] != "1" {
+			return false
+		}
+		if cmd[3] != "submissions:contest:2:user:3" {
+			return false
+		}
+		if cmd[4] != "60000" {
+			return false
+		}
+		return true
+	})
+
+	t.Run("success", func(t *testing.T) {
+		ctx := context.Background()
+		client.EXPECT().Do(ctx, matcher).Return(mock.Result(mock.ValkeyInt64(4)))
+
+		count, err := limitsRepo.CountSubmission(ctx, 2, 3, time.Minute)
+		require.NoError(t, err)
+		require.Equal(t, int64(4), count)
+	})
+
+	t.Run("unhandled error", func(t *testing.T) {
+		ctx := context.Background()
+		client.EXPECT().Do(ctx, matcher).Return(mock.ErrorResult(context.DeadlineExceeded))
+
+		_, err := limitsRepo.CountSubmission(ctx, 2, 3, time.Minute)
+		require.ErrorIs(t, err, pkg.ErrUnhandled)
+	})
+}
+
+func TestValkeyRepository_UncountSubmission(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := mock.NewClient(ctrl)
+	limitsRepo := repository.NewValkeyRepository(client)
+
+	matcher := mock.MatchFn(func(cmd []string) bool {
+		if cmd[0] != "EVALSHA" {
+			return false
+		}
+		if cmd[2] != "1" {
+			return false
+		}
+		if cmd[3] != "submissions:contest:2:user:3" {
+			return false
+		}
+		return true
+	})
+
+	t.Run("success", func(t *testing.T) {
+		ctx := context.Background()
+		client.EXPECT().Do(ctx, matcher).Return(mock.Result(mock.ValkeyInt64(3)))
+
+		err := limitsRepo.UncountSubmission(ctx, 2, 3)
+		require.NoError(t, err)
+	})
+
+	t.Run("unhandled error", func(t *testing.T) {
+		ctx := context.Background()
+		client.EXPECT().Do(ctx, matcher).Return(mock.ErrorResult(context.DeadlineExceeded))
+
+		err := limitsRepo.UncountSubmission(ctx, 2, 3)
+		require.ErrorIs(t, err, pkg.ErrUnhandled)
+	})
+}
@@ -7,7 +7,13 @@ import (
 
 type UseCase interface {
 	GetSolution(ctx context.Context, id int32) (*models.Solution, error)
+	DownloadArchive(ctx context.Context, sol *models.Solution) ([]byte, error)
+	ReadSource(ctx context.Context, key string, size int32, hash string) (string, error)
 	CreateSolution(ctx context.Context, creation *models.SolutionCreation) (int32, error)
 	UpdateSolution(ctx context.Context, id int32, update *models.SolutionUpdate) error
 	ListSolutions(ctx context.Context, filter models.SolutionsFilter) (*models.SolutionsList, error)
+	RunSolution(ctx context.Context, run *models.SolutionRun) (*models.SolutionRunResult, error)
+	DiffSolutions(ctx context.Context, from, to int32, ignoreWhitespace bool) (*models.SolutionsDiff, error)
+	RejudgeSolutions(ctx context.Context, ids []int32) error
+	JudgeQueues(ctx context.Context) ([]*models.JudgeQueue, error)
 }
@@ -0,0 +1,41 @@
+package usecase
+
+import (
+	"context"
+	"github.com/Vyacheslav1557/tester/internal/solutions"
+	"strings"
+)
+
+// MigrateSources moves the sources still kept in the database to the storage batch by batch
+// and returns how many have been moved. A source is dropped from the database only once it
+// is uploaded, so the solutions stay readable all along and the migration can be resumed.
+func MigrateSources(
+	ctx context.Context,
+	solutionsRepo solutions.Repository,
+	storage solutions.SolutionStorage,
+	batch int32,
+) (int, error) {
+	moved := 0
+	for {
+		sources, err := solutionsRepo.ListLegacySources(ctx, batch)
+		if err != nil {
+			return moved, err
+		}
+		if len(sources) == 0 {
+			return moved, nil
+		}
+
+		for _, source := range sources {
+			key, err := storage.UploadSource(ctx, source.Id, strings.NewReader(source.Solution))
+			if err != nil {
+				return moved, err
+			}
+
+			err = solutionsRepo.MoveSource(ctx, source.Id, key)
+			if err != nil {
+				return moved, err
+			}
+			moved++
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package usecase_test
+
+import (
+	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/Vyacheslav1557/tester/internal/solutions/usecase"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestScore(t *testing.T) {
+	t.Parallel()
+
+	groups := &models.Meta{Groups: []models.TestGroup{
+		{Name: "1", Points: 30, Policy: models.PointsCompleteGroup, Tests: []int{0, 1}},
+		{Name: "2", Points: 40, Policy: models.PointsEachTest, Tests: []int{2, 3}, TestPoints: []float64{10, 30}},
+		{Name: "", Points: 30, Policy: models.PointsEachTest, Tests: []int{4}, TestPoints: []float64{30}},
+	}}
+
+	for name, tc := range map[string]struct {
+		strategy models.Strategy
+		meta     *models.Meta
+		scores   []float64
+		expected int32
+	}{
+		"icpc accepted":         {models.StrategyICPC, &models.Meta{}, []float64{1, 1}, 100},
+		"icpc partial":          {models.StrategyICPC, &models.Meta{}, []float64{1, 0.5}, 0},
+		"ioi no groups":         {models.StrategyIOI, &models.Meta{}, []float64{1, 0, 0.5, 1}, 62},
+		"ioi no tests":          {models.StrategyIOI, &models.Meta{}, nil, 100},
+		"all passed":            {models.StrategyIOI, groups, []float64{1, 1, 1, 1, 1}, 100},
+		"complete group failed": {models.StrategyIOI, groups, []float64{1, 0, 1, 1, 1}, 70},
+		"complete group share":  {models.StrategyIOI, groups, []float64{1, 0.5, 0, 0, 0}, 15},
+		"each test":             {models.StrategyIOI, groups, []float64{0, 0, 0, 1, 0}, 30},
+		"each test share":       {models.StrategyIOI, groups, []float64{0, 0, 0.5, 0, 0}, 5},
+		"outside groups":        {models.StrategyIOI, groups, []float64{0, 0, 0, 0, 1}, 30},
+		"stopped early":         {models.StrategyIOI, groups, []float64{1, 1, 1}, 40},
+		"group without policy": {models.StrategyIOI, &models.Meta{Groups: []models.TestGroup{
+			{Name: "1", Points: 100, Tests: []int{0, 1}},
+		}}, []float64{1, 0}, 0},
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.expected, usecase.Score(tc.strategy, tc.meta, tc.scores))
+		})
+	}
+}
@@ -1,15 +1,24 @@
 package usecase
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"github.com/Vyacheslav1557/tester/internal/contests"
 	"github.com/Vyacheslav1557/tester/internal/models"
 	"github.com/Vyacheslav1557/tester/internal/problems"
 	"github.com/Vyacheslav1557/tester/internal/solutions"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"github.com/Vyacheslav1557/tester/pkg/diff"
 	"github.com/Vyacheslav1557/tester/pkg/tester"
+	"io"
+	"math"
+	"strings"
+	"sync"
 	"time"
+
+	"go.uber.org/zap"
 )
 
 type Publisher interface {
@@ -18,35 +27,137 @@ type Publisher interface {
 
 type Tester interface {
 	Test(ctx context.Context, packet tester.Packet, s tester.Solution) <-chan tester.TestingMessage
+	Run(ctx context.Context, s tester.Solution, in io.Reader, tl, ml int64) (*tester.RunResult, error)
+	QueueStats() []tester.QueueStats
 }
 
 type UseCase struct {
 	solutionsRepo solutions.Repository
+	limitsRepo    solutions.LimitsRepository
+	archivesRepo  solutions.ArchivesRepository
+	storage       solutions.SolutionStorage
 	problemsUC    problems.UseCase
+	contestsUC    contests.UseCase
 	pub           Publisher
 	tester        Tester
+	runLimiter    *runLimiter
+	judgeRetries  int
+	submitLimit   models.SubmitLimit
+	logger        *zap.Logger
 }
 
 func NewUseCase(
 	solutionsRepo solutions.Repository,
+	limitsRepo solutions.LimitsRepository,
+	archivesRepo solutions.ArchivesRepository,
+	storage solutions.SolutionStorage,
 	problemsUC problems.UseCase,
+	contestsUC contests.UseCase,
 	pub Publisher,
 	tester Tester,
+	judgeRetries int,
+	submitLimit models.SubmitLimit,
+	logger *zap.Logger,
 ) *UseCase {
 	return &UseCase{
 		solutionsRepo: solutionsRepo,
+		limitsRepo:    limitsRepo,
+		archivesRepo:  archivesRepo,
+		storage:       storage,
 		problemsUC:    problemsUC,
+		contestsUC:    contestsUC,
 		pub:           pub,
 		tester:        tester,
+		runLimiter:    newRunLimiter(runInterval),
+		judgeRetries:  judgeRetries,
+		submitLimit:   submitLimit,
+		logger:        logger,
 	}
 }
 
+// GetSolution reads the source from the object storage, or from the database
+// if the source has not been moved yet
 func (uc *UseCase) GetSolution(ctx context.Context, id int32) (*models.Solution, error) {
-	return uc.solutionsRepo.GetSolution(ctx, id)
+	sol, err := uc.solutionsRepo.GetSolution(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if sol.SourceKey != nil {
+		sol.Solution, err = uc.ReadSource(ctx, *sol.SourceKey, sol.SourceSize, sol.SourceHash)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sol, nil
+}
+
+// ReadSource reads exactly the recorded size of the source and checks it against the hash
+func (uc *UseCase) ReadSource(ctx context.Context, key string, size int32, hash string) (string, error) {
+	const op = "UseCase.ReadSource"
+
+	rc, err := uc.storage.DownloadSource(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	// one byte past the recorded size is enough to tell the source is not the one submitted
+	data, err := io.ReadAll(io.LimitReader(rc, int64(size)+1))
+	if err != nil {
+		return "", pkg.Wrap(pkg.ErrInternal, err, op, "failed to read source")
+	}
+	if len(data) != int(size) {
+		return "", pkg.Wrap(pkg.ErrInternal, nil, op, "source size mismatch")
+	}
+
+	if models.SourceHash(data) != hash {
+		return "", pkg.Wrap(pkg.ErrInternal, nil, op, "source hash mismatch")
+	}
+
+	return string(data), nil
+}
+
+// diffContext is the number of unchanged lines shown around the changes
+const diffContext = 3
+
+// DiffSolutions compares the sources of two solutions
+func (uc *UseCase) DiffSolutions(ctx context.Context, from, to int32, ignoreWhitespace bool) (*models.SolutionsDiff, error) {
+	const op = "UseCase.DiffSolutions"
+
+	fromSolution, err := uc.GetSolution(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+
+	toSolution, err := uc.GetSolution(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if fromSolution.ArchiveKey != nil || toSolution.ArchiveKey != nil {
+		return nil, pkg.Wrap(pkg.ErrBadInput, nil, op, "archives cannot be compared")
+	}
+
+	key := diff.Identity
+	if ignoreWhitespace {
+		key = diff.IgnoreWhitespace
+	}
+
+	edits := diff.Lines(diff.SplitLines(fromSolution.Solution), diff.SplitLines(toSolution.Solution), key)
+
+	return &models.SolutionsDiff{
+		From: fromSolution,
+		To:   toSolution,
+		Diff: diff.Unified(edits, fmt.Sprintf("solution %d", from), fmt.Sprintf("solution %d", to), diffContext),
+	}, nil
 }
 
 func (uc *UseCase) CreateSolution(ctx context.Context, creation *models.SolutionCreation) (int32, error) {
-	id, err := uc.solutionsRepo.CreateSolution(ctx, creation)
+	const op = "UseCase.CreateSolution"
+
+	contestProblem, err := uc.contestsUC.GetContestProblem(ctx, creation.ContestId, creation.ProblemId)
 	if err != nil {
 		return 0, err
 	}
@@ -56,8 +167,66 @@ func (uc *UseCase) CreateSolution(ctx context.Context, creation *models.Solution
 		return 0, err
 	}
 
+	switch {
+	case problem.Type == models.ProblemOutputOnly:
+		// the answers are checked as they are, the language does not matter
+		if creation.Archive == nil {
+			return 0, pkg.Wrap(pkg.ErrBadInput, nil, op, "answers must be submitted as a zip of 01.out, 02.out, ...")
+		}
+
+		err = tester.CheckOutputs(creation.Archive)
+		if err != nil {
+			return 0, err
+		}
+	case !contestProblem.Languages.Contains(creation.Language):
+		return 0, pkg.Wrap(pkg.ErrBadInput, nil, op,
+			fmt.Sprintf("language %d is not allowed for this problem", creation.Language))
+	case creation.Archive != nil && len(problem.Graders) != 0:
+		return 0, pkg.Wrap(pkg.ErrBadInput, nil, op, "solutions of problems with a grader must be single files")
+	case creation.Archive != nil:
+		err = tester.CheckArchive(creation.Language, creation.Archive)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if creation.Pretest && len(problem.Samples) == 0 {
+		return 0, pkg.Wrap(pkg.ErrBadInput, nil, op, "problem has no sample tests")
+	}
+
+	if creation.Archive == nil {
+		creation.SourceHash = models.SourceHash([]byte(creation.Solution))
+		creation.SourceSize = int32(len(creation.Solution))
+	}
+
+	if !creation.Unlimited {
+		err = uc.checkSubmission(ctx, creation, contestProblem)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	id, err := uc.solutionsRepo.CreateSolution(ctx, creation)
+	if err != nil {
+		uc.uncountSubmission(ctx, creation, contestProblem)
+		return 0, err
+	}
+
+	err = uc.storeSource(ctx, id, creation)
+	if err != nil {
+		// a solution without its source can be neither judged nor shown,
+		// it is dropped even if the request has been canceled meanwhile
+		dErr := uc.solutionsRepo.DeleteSolution(context.WithoutCancel(ctx), id)
+		if dErr != nil {
+			uc.logger.Error("failed to delete the solution without a source",
+				zap.Int32("solution", id), zap.Error(dErr))
+		}
+		uc.uncountSubmission(ctx, creation, contestProblem)
+		return 0, err
+	}
+
 	// if there are no tests, just accept the solution
-	if problem.Meta.Count == 0 {
+	if !creation.Pretest && problem.Meta.Count == 0 {
 		err := uc.solutionsRepo.UpdateSolution(ctx, id, &models.SolutionUpdate{
 			State:      models.Accepted,
 			Score:      100,
@@ -71,35 +240,250 @@ func (uc *UseCase) CreateSolution(ctx context.Context, creation *models.Solution
 		return 0, nil
 	}
 
-	zipPath, err := uc.problemsUC.DownloadTestsArchive(ctx, problem.Id)
+	sol, err := uc.solutionsRepo.GetSolution(ctx, id)
 	if err != nil {
 		return 0, err
 	}
+	// the source has just been uploaded, there is no need to read it back
+	sol.Solution = creation.Solution
+
+	err = uc.startTesting(ctx, problem, sol, contestProblem, priority(contestProblem.ContestKind))
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// storeSource uploads the source or the archive of the solution and links the solution to it
+func (uc *UseCase) storeSource(ctx context.Context, id int32, creation *models.SolutionCreation) error {
+	if creation.Archive != nil {
+		key, err := uc.archivesRepo.UploadArchive(ctx, id, bytes.NewReader(creation.Archive))
+		if err != nil {
+			return err
+		}
+
+		return uc.solutionsRepo.SetArchiveKey(ctx, id, key)
+	}
 
+	key, err := uc.storage.UploadSource(ctx, id, strings.NewReader(creation.Solution))
+	if err != nil {
+		return err
+	}
+
+	return uc.solutionsRepo.SetSourceKey(ctx, id, key)
+}
+
+// checkSubmission rejects resubmissions of already judged solutions
+// and enforces the submission limit of the contest
+func (uc *UseCase) checkSubmission(
+	ctx context.Context,
+	creation *models.SolutionCreation,
+	contestProblem *models.ContestProblem,
+) error {
+	const op = "UseCase.checkSubmission"
+
+	// the sources of archives are not in the database to compare them
+	if creation.Archive == nil {
+		duplicate, err := uc.solutionsRepo.FindJudgedDuplicate(ctx, creation)
+		if err != nil {
+			return err
+		}
+		if duplicate != 0 {
+			return pkg.Wrap(pkg.ErrConflict, nil, op,
+				fmt.Sprintf("the same solution has already been judged, see solution %d", duplicate))
+		}
+	}
+
+	limit, ok := models.EffectiveSubmitLimit(uc.submitLimit,
+		contestProblem.ContestSubmitLimit, contestProblem.ContestSubmitWindow)
+	if !ok {
+		return nil
+	}
+
+	count, err := uc.limitsRepo.CountSubmission(ctx, creation.ContestId, creation.UserId, limit.Window)
+	if err != nil {
+		return err
+	}
+	if count > int64(limit.Count) {
+		return pkg.Wrap(pkg.ErrTooManyRequests, nil, op,
+			fmt.Sprintf("at most %d solutions per %s are allowed, try again later", limit.Count, limit.Window))
+	}
+
+	return nil
+}
+
+// uncountSubmission gives back the quota taken by checkSubmission
+// for a submission which has not been stored in the end
+func (uc *UseCase) uncountSubmission(
+	ctx context.Context,
+	creation *models.SolutionCreation,
+	contestProblem *models.ContestProblem,
+) {
+	if creation.Unlimited {
+		return
+	}
+
+	_, ok := models.EffectiveSubmitLimit(uc.submitLimit,
+		contestProblem.ContestSubmitLimit, contestProblem.ContestSubmitWindow)
+	if !ok {
+		return
+	}
+
+	err := uc.limitsRepo.UncountSubmission(context.WithoutCancel(ctx), creation.ContestId, creation.UserId)
+	if err != nil {
+		uc.logger.Error("failed to uncount the submission",
+			zap.Int32("contest", creation.ContestId), zap.Int32("user", creation.UserId), zap.Error(err))
+	}
+}
+
+// startTesting downloads the tests, only the samples for a pretest,
+// and tests the solution in the background
+func (uc *UseCase) startTesting(
+	ctx context.Context,
+	problem *models.Problem,
+	sol *models.Solution,
+	contestProblem *models.ContestProblem,
+	priority tester.Priority,
+) error {
 	packet := Packet{
-		contestId:   creation.ContestId,
+		contestId:   sol.ContestId,
 		problemId:   problem.Id,
 		updatedAt:   problem.UpdatedAt.Unix(),
-		zipPath:     zipPath,
+		samples:     sol.Pretest,
 		timeLimit:   int64(problem.TimeLimit),
 		memoryLimit: int64(problem.MemoryLimit),
 		meta:        &problem.Meta,
+		strategy:    contestProblem.Strategy,
+		priority:    priority,
+		problemType: problem.Type,
+	}
+
+	var err error
+	if sol.Pretest {
+		meta := problem.Samples.Meta()
+		meta.Checker = problem.Meta.Checker
+		packet.meta = &meta
+		packet.zipPath, err = uc.problemsUC.DownloadSamplesArchive(ctx, problem.Id)
+	} else {
+		packet.zipPath, err = uc.problemsUC.DownloadTestsArchive(ctx, problem.Id)
+	}
+	if err != nil {
+		return err
 	}
 
 	solution := &Solution{
-		solution: []byte(creation.Solution),
-		language: creation.Language,
-		id:       id,
+		solution: []byte(sol.Solution),
+		language: sol.Language,
+		id:       sol.Id,
+		userId:   sol.UserId,
 	}
 
-	sol, err := uc.solutionsRepo.GetSolution(ctx, id)
-	if err != nil {
-		return 0, err
+	if grader := problem.Graders.Get(sol.Language); grader != nil {
+		solution.grader = []byte(grader.Source)
+	}
+
+	if sol.ArchiveKey != nil {
+		solution.archive = true
+		solution.solution, err = uc.DownloadArchive(ctx, sol)
+		if err != nil {
+			return err
+		}
 	}
 
 	go uc.test(ctx, packet, solution, sol)
 
-	return id, err
+	return nil
+}
+
+// maxArchiveSize bounds what is read from the object storage into memory
+const maxArchiveSize = 64 * 1024 * 1024 // 64 MB
+
+// DownloadArchive reads the zip of a multi-file solution
+func (uc *UseCase) DownloadArchive(ctx context.Context, sol *models.Solution) ([]byte, error) {
+	const op = "UseCase.DownloadArchive"
+
+	if sol.ArchiveKey == nil {
+		return nil, pkg.Wrap(pkg.ErrNotFound, nil, op, "solution is not an archive")
+	}
+
+	rc, err := uc.archivesRepo.DownloadArchive(ctx, *sol.ArchiveKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, maxArchiveSize+1))
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to read archive")
+	}
+	if len(data) > maxArchiveSize {
+		return nil, pkg.Wrap(pkg.ErrInternal, nil, op, "archive is too large")
+	}
+
+	return data, nil
+}
+
+// priority puts the solutions of live contests first, then the homework and practice
+func priority(kind models.ContestKind) tester.Priority {
+	switch kind {
+	case models.ContestLive:
+		return tester.PriorityContest
+	case models.ContestHomework:
+		return tester.PriorityHomework
+	default:
+		return tester.PriorityPractice
+	}
+}
+
+// JudgeQueues returns the state of the judge queue of every priority
+func (uc *UseCase) JudgeQueues(ctx context.Context) ([]*models.JudgeQueue, error) {
+	stats := uc.tester.QueueStats()
+
+	queues := make([]*models.JudgeQueue, len(stats))
+	for i, s := range stats {
+		queues[i] = &models.JudgeQueue{
+			Priority:    s.Priority.String(),
+			Depth:       int32(s.Depth),
+			OldestWait:  s.OldestWait,
+			AverageWait: s.AverageWait,
+		}
+	}
+
+	return queues, nil
+}
+
+// RejudgeSolutions tests the solutions once again from scratch
+func (uc *UseCase) RejudgeSolutions(ctx context.Context, ids []int32) error {
+	for _, id := range ids {
+		sol, err := uc.GetSolution(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		contestProblem, err := uc.contestsUC.GetContestProblem(ctx, sol.ContestId, sol.ProblemId)
+		if err != nil {
+			return err
+		}
+
+		problem, err := uc.problemsUC.GetProblemById(ctx, sol.ProblemId)
+		if err != nil {
+			return err
+		}
+
+		err = uc.solutionsRepo.UpdateSolution(ctx, id, &models.SolutionUpdate{State: models.Saved})
+		if err != nil {
+			return err
+		}
+		sol.State, sol.Score, sol.TimeStat, sol.MemoryStat, sol.FailedTest = models.Saved, 0, 0, 0, 0
+
+		err = uc.startTesting(ctx, problem, sol, contestProblem, tester.PriorityRejudge)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (uc *UseCase) UpdateSolution(ctx context.Context, id int32, update *models.SolutionUpdate) error {
@@ -110,9 +494,110 @@ func (uc *UseCase) ListSolutions(ctx context.Context, filter models.SolutionsFil
 	return uc.solutionsRepo.ListSolutions(ctx, filter)
 }
 
-func (uc *UseCase) test(ctx context.Context, packet tester.Packet, s tester.Solution, sol *models.Solution) {
-	ch := uc.tester.Test(ctx, packet, s)
+const (
+	runInterval  = 5 * time.Second // minimal interval between custom invocations of a user
+	defaultRunTL = 1000            // ms
+	defaultRunML = 256             // MB
+)
+
+// RunSolution executes the solution once on the user's input, nothing is stored
+func (uc *UseCase) RunSolution(ctx context.Context, run *models.SolutionRun) (*models.SolutionRunResult, error) {
+	const op = "UseCase.RunSolution"
+
+	if !uc.runLimiter.Allow(run.UserId) {
+		return nil, pkg.Wrap(pkg.ErrTooManyRequests, nil, op, "too many runs, try again later")
+	}
+
+	solution := &Solution{
+		solution: []byte(run.Solution),
+		language: run.Language,
+		userId:   run.UserId,
+	}
+
+	tl, ml := int64(defaultRunTL), int64(defaultRunML)
+	if run.ProblemId != nil {
+		if run.ContestId == nil {
+			return nil, pkg.Wrap(pkg.ErrBadInput, nil, op, "contest id is required")
+		}
+
+		contestProblem, err := uc.contestsUC.GetContestProblem(ctx, *run.ContestId, *run.ProblemId)
+		if err != nil {
+			return nil, err
+		}
+
+		if !contestProblem.Languages.Contains(run.Language) {
+			return nil, pkg.Wrap(pkg.ErrBadInput, nil, op,
+				fmt.Sprintf("language %d is not allowed for this problem", run.Language))
+		}
+
+		tl, ml = int64(contestProblem.TimeLimit), int64(contestProblem.MemoryLimit)
+
+		if grader := contestProblem.Graders.Get(run.Language); grader != nil {
+			solution.grader = []byte(grader.Source)
+		}
+	}
+
+	res, err := uc.tester.Run(ctx, solution, strings.NewReader(run.Input), tl, ml)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.SolutionRunResult{
+		State:    res.State,
+		Stdout:   res.Stdout,
+		Stderr:   res.Stderr,
+		ExitCode: int32(res.ExitCode),
+	}
+
+	if res.Metrics != nil {
+		result.TimeStat = int32(res.Metrics.CPUTime.Milliseconds())
+		result.MemoryStat = int32(res.Metrics.Memory)
+	}
+
+	return result, nil
+}
+
+// runLimiter allows a user one custom invocation per interval
+type runLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	last     map[int32]time.Time
+}
+
+func newRunLimiter(interval time.Duration) *runLimiter {
+	return &runLimiter{
+		interval: interval,
+		last:     make(map[int32]time.Time),
+	}
+}
+
+func (l *runLimiter) Allow(userId int32) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
 
+	if last, ok := l.last[userId]; ok && now.Sub(last) < l.interval {
+		return false
+	}
+
+	// forget users whose interval is over, so that the map does not grow forever
+	for id, last := range l.last {
+		if now.Sub(last) >= l.interval {
+			delete(l.last, id)
+		}
+	}
+
+	l.last[userId] = now
+
+	return true
+}
+
+const judgeBackoff = 5 * time.Second // before the first retry, doubled after every next one
+
+// test judges the solution and saves the verdict. Failures of the judge itself
+// are retried, the solution gets JudgementFailed if all attempts have failed.
+func (uc *UseCase) test(ctx context.Context, packet tester.Packet, s tester.Solution, sol *models.Solution) {
 	sli := SolutionsListItem{
 		Id: sol.Id,
 
@@ -125,6 +610,8 @@ func (uc *UseCase) test(ctx context.Context, packet tester.Packet, s tester.Solu
 		TimeStat:   sol.TimeStat,
 		MemoryStat: sol.MemoryStat,
 		Language:   sol.Language,
+		Pretest:    sol.Pretest,
+		FailedTest: sol.FailedTest,
 
 		ProblemId:    sol.ProblemId,
 		ProblemTitle: sol.ProblemTitle,
@@ -143,6 +630,99 @@ func (uc *UseCase) test(ctx context.Context, packet tester.Packet, s tester.Solu
 		Solution:    sli,
 	})
 
+	var (
+		solutionUpdate *models.SolutionUpdate
+		err            error
+	)
+
+	backoff := judgeBackoff
+	for attempt := 0; ; attempt++ {
+		solutionUpdate, err = uc.judge(ctx, packet, s, sli)
+		if err == nil || attempt == uc.judgeRetries || ctx.Err() != nil {
+			break
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+		}
+		backoff *= 2
+	}
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	if err != nil {
+		solutionUpdate = &models.SolutionUpdate{
+			State:       models.JudgementFailed,
+			Diagnostics: models.Diagnostics{Error: err.Error()},
+		}
+	}
+
+	solutionUpdate, err = uc.saveVerdict(ctx, s.Id(), solutionUpdate)
+	if err != nil {
+		uc.logger.Error("failed to save the verdict", zap.Int32("solution", s.Id()), zap.Error(err))
+		return
+	}
+
+	sli.State = solutionUpdate.State
+	sli.Score = solutionUpdate.Score
+	sli.TimeStat = solutionUpdate.TimeStat
+	sli.MemoryStat = solutionUpdate.MemoryStat
+	sli.FailedTest = solutionUpdate.FailedTest
+
+	uc.publish(packet.ContestId(),
+		&Message{
+			MessageType: MessageTypeUpdate,
+			Solution:    sli,
+		})
+}
+
+// saveVerdict retries saving the verdict the same way the judging is retried. If it still
+// fails, the solution gets JudgementFailed instead, so that it can be rejudged later.
+func (uc *UseCase) saveVerdict(ctx context.Context, id int32, update *models.SolutionUpdate) (*models.SolutionUpdate, error) {
+	var err error
+
+	backoff := judgeBackoff
+	for attempt := 0; ; attempt++ {
+		err = uc.solutionsRepo.UpdateSolution(ctx, id, update)
+		if err == nil || attempt == uc.judgeRetries || ctx.Err() != nil {
+			break
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+		}
+		backoff *= 2
+	}
+	if err == nil || update.State == models.JudgementFailed {
+		return update, err
+	}
+
+	uc.logger.Error("failed to save the verdict, the judgement is marked failed",
+		zap.Int32("solution", id), zap.Error(err))
+
+	failed := &models.SolutionUpdate{
+		State:       models.JudgementFailed,
+		Diagnostics: models.Diagnostics{Error: "failed to save the verdict: " + err.Error()},
+	}
+	return failed, uc.solutionsRepo.UpdateSolution(ctx, id, failed)
+}
+
+// judge runs the tests once, an error is a failure of the judge rather than of the solution
+func (uc *UseCase) judge(
+	ctx context.Context,
+	packet tester.Packet,
+	s tester.Solution,
+	sli SolutionsListItem,
+) (*models.SolutionUpdate, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ch := uc.tester.Test(ctx, packet, s)
+
 	solutionUpdate := models.SolutionUpdate{
 		State:      models.Saved,
 		Score:      0,
@@ -150,69 +730,143 @@ func (uc *UseCase) test(ctx context.Context, packet tester.Packet, s tester.Solu
 		MemoryStat: 0,
 	}
 
-	testsPassed := 0
-	testsPassedExpected := packet.Meta().Count
+	meta := packet.Meta()
+	scores := make([]float64, meta.Count) // shares of the points of the tests
 
+	var judgeErr error
 	for msg := range ch {
-		if msg.Details != "" {
+		details := msg.Details
+		if msg.State != 0 && msg.State != models.Accepted && msg.Test != 0 {
+			details = fmt.Sprintf("%s on test %d", msg.State, msg.Test)
+		}
+
+		if details != "" {
 			uc.publish(packet.ContestId(), &Message{
 				MessageType: MessageTypeUpdate,
 				Solution:    sli,
-				Message:     &msg.Details,
+				Message:     &details,
+				Test:        int32(msg.Test),
 			})
 		}
 
-		if msg.Err != nil {
-			var stErr *tester.StateErr
-			if errors.As(msg.Err, &stErr) {
-				solutionUpdate.State = stErr.State
-				break
-			}
-
-			fmt.Println("something really bad happened here")
-			break
-		}
-
 		if msg.Metrics != nil {
-			testsPassed++
-
 			// doing this way we get the max over all tests
 			solutionUpdate.MemoryStat = max(
 				solutionUpdate.MemoryStat,
-				int32(msg.Metrics.MaximumResidentSetSize),
+				int32(msg.Metrics.Memory),
 			)
 			solutionUpdate.TimeStat = max(
 				solutionUpdate.TimeStat,
-				int32(msg.Metrics.ElapsedTime.Milliseconds()),
+				int32(msg.Metrics.CPUTime.Milliseconds()),
 			)
 		}
+
+		switch {
+		case msg.State == models.Accepted:
+			scores[msg.Test-1] = msg.Score
+		case msg.State != 0:
+			// a wrong answer may still get a share of the points of the test
+			if msg.Test != 0 {
+				scores[msg.Test-1] = msg.Score
+			}
+
+			// IOI tests finish in any order, the verdict is of the first failed one
+			if solutionUpdate.State == models.Saved || int32(msg.Test) < solutionUpdate.FailedTest {
+				solutionUpdate.State, solutionUpdate.FailedTest = msg.State, int32(msg.Test)
+				solutionUpdate.Diagnostics = diagnostics(msg)
+			}
+		case msg.Err != nil:
+			// the rest of the tests are canceled, their errors are of no interest
+			if judgeErr == nil {
+				judgeErr = msg.Err
+			}
+			cancel()
+		}
 	}
 
-	if testsPassed != testsPassedExpected && solutionUpdate.State == models.Saved {
-		fmt.Println("something bad had happened")
-		return
+	if judgeErr != nil {
+		return nil, judgeErr
 	}
 
-	if testsPassed == testsPassedExpected {
-		solutionUpdate.Score = 100
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if solutionUpdate.State == models.Saved {
 		solutionUpdate.State = models.Accepted
 	}
+	solutionUpdate.Score = score(packet.Strategy(), meta, scores)
 
-	if err := uc.solutionsRepo.UpdateSolution(ctx, s.Id(), &solutionUpdate); err != nil {
-		fmt.Println(err)
-		return
+	return &solutionUpdate, nil
+}
+
+// diagnostics keeps the compiler output on CE, and the stderr
+// and the exit status of the failed test on RE
+func diagnostics(msg tester.TestingMessage) models.Diagnostics {
+	switch msg.State {
+	case models.GotCE:
+		return models.Diagnostics{CompileLog: msg.Stderr}
+	case models.GotRE:
+		d := models.Diagnostics{Stderr: msg.Stderr}
+		if msg.Metrics != nil {
+			d.ExitCode = int32(msg.Metrics.ExitCode)
+			d.Signal = int32(msg.Metrics.Signal())
+		}
+		return d
+	default:
+		return models.Diagnostics{}
 	}
+}
 
-	sli.State = solutionUpdate.State
-	sli.Score = solutionUpdate.Score
-	sli.TimeStat = solutionUpdate.TimeStat
-	sli.MemoryStat = solutionUpdate.MemoryStat
+// score is 100 for an accepted ICPC solution. IOI solutions get the points of
+// the groups they have passed, or the share of the tests. The checker may give
+// a share of the points of a test, a complete group gets the least share of its
+// tests and each-test groups the points of every test times its share.
+func score(strategy models.Strategy, meta *models.Meta, scores []float64) int32 {
+	if strategy != models.StrategyIOI {
+		for _, s := range scores {
+			if s < 1 {
+				return 0
+			}
+		}
+		return 100
+	}
 
-	uc.publish(packet.ContestId(),
-		&Message{
-			MessageType: MessageTypeUpdate,
-			Solution:    sli,
-		})
+	if len(meta.Groups) == 0 {
+		if len(scores) == 0 {
+			return 100
+		}
+
+		var sum float64
+		for _, s := range scores {
+			sum += s
+		}
+		return int32(100 * sum / float64(len(scores)))
+	}
+
+	var points float64
+	for _, group := range meta.Groups {
+		if group.Policy == models.PointsEachTest {
+			for i, test := range group.Tests {
+				if test < len(scores) && i < len(group.TestPoints) {
+					points += group.TestPoints[i] * scores[test]
+				}
+			}
+			continue
+		}
+
+		share := 1.0
+		for _, test := range group.Tests {
+			if test >= len(scores) {
+				share = 0
+				break
+			}
+			share = min(share, scores[test])
+		}
+
+		points += float64(group.Points) * share
+	}
+	return int32(math.Round(points))
 }
 
 func (uc *UseCase) publish(contestId int32, msg *Message) error {
@@ -228,10 +882,14 @@ type Packet struct {
 	contestId   int32
 	problemId   int32
 	updatedAt   int64
+	samples     bool
 	zipPath     string
 	timeLimit   int64
 	memoryLimit int64
 	meta        *models.Meta
+	strategy    models.Strategy
+	priority    tester.Priority
+	problemType models.ProblemType
 }
 
 func (p Packet) ContestId() int32 {
@@ -239,6 +897,9 @@ func (p Packet) ContestId() int32 {
 }
 
 func (p Packet) UniquePacketName() string {
+	if p.samples {
+		return fmt.Sprintf("%d_%d_samples", p.problemId, p.updatedAt)
+	}
 	return fmt.Sprintf("%d_%d", p.problemId, p.updatedAt)
 }
 
@@ -258,10 +919,33 @@ func (p Packet) Meta() *models.Meta {
 	return p.meta
 }
 
+func (p Packet) Strategy() models.Strategy {
+	return p.strategy
+}
+
+func (p Packet) Priority() tester.Priority {
+	return p.priority
+}
+
+func (p Packet) Type() models.ProblemType {
+	return p.problemType
+}
+
 type Solution struct {
 	solution []byte
+	archive  bool   // solution is a zip of files
+	grader   []byte // jury code the solution is compiled with, nil if there is none
 	language models.LanguageName
 	id       int32
+	userId   int32
+}
+
+func (s *Solution) Archive() bool {
+	return s.archive
+}
+
+func (s *Solution) Grader() []byte {
+	return s.grader
 }
 
 func (s *Solution) Solution() []byte {
@@ -276,6 +960,10 @@ func (s *Solution) Id() int32 {
 	return s.id
 }
 
+func (s *Solution) UserId() int32 {
+	return s.userId
+}
+
 const (
 	MessageTypeCreate = "CREATE"
 	MessageTypeUpdate = "UPDATE"
@@ -294,6 +982,8 @@ type SolutionsListItem struct {
 	TimeStat   int32               `json:"time_stat"`
 	MemoryStat int32               `json:"memory_stat"`
 	Language   models.LanguageName `json:"language"`
+	Pretest    bool                `json:"pretest"`
+	FailedTest int32               `json:"failed_test"`
 
 	ProblemId    int32  `json:"problem_id"`
 	ProblemTitle string `json:"problem_title"`
@@ -310,5 +1000,6 @@ type SolutionsListItem struct {
 type Message struct {
 	MessageType string            `json:"message_type"`
 	Message     *string           `json:"message,omitempty"`
+	Test        int32             `json:"test,omitempty"` // 1-based number of the test the message is about
 	Solution    SolutionsListItem `json:"solution"`
 }
@@ -0,0 +1,261 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Vyacheslav1557/tester/internal/contests"
+	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/Vyacheslav1557/tester/internal/problems"
+	"github.com/Vyacheslav1557/tester/internal/solutions"
+	"github.com/Vyacheslav1557/tester/internal/solutions/usecase"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRepository keeps the legacy sources in memory, the other methods are not used
+type fakeRepository struct {
+	solutions.Repository
+
+	legacy  map[int32]string
+	keys    map[int32]string
+	deleted []int32
+}
+
+func (r *fakeRepository) CreateSolution(context.Context, *models.SolutionCreation) (int32, error) {
+	return 7, nil
+}
+
+func (r *fakeRepository) DeleteSolution(_ context.Context, id int32) error {
+	r.deleted = append(r.deleted, id)
+	return nil
+}
+
+func (r *fakeRepository) SetSourceKey(_ context.Context, id int32, key string) error {
+	r.keys[id] = key
+	return nil
+}
+
+func (r *fakeRepository) ListLegacySources(_ context.Context, limit int32) ([]*models.LegacySource, error) {
+	sources := make([]*models.LegacySource, 0)
+	for id, source := range r.legacy {
+		sources = append(sources, &models.LegacySource{Id: id, Solution: source})
+	}
+
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].Id < sources[j].Id
+	})
+
+	return sources[:min(len(sources), int(limit))], nil
+}
+
+func (r *fakeRepository) MoveSource(_ context.Context, id int32, key string) error {
+	delete(r.legacy, id)
+	r.keys[id] = key
+	return nil
+}
+
+func (r *fakeRepository) FindJudgedDuplicate(context.Context, *models.SolutionCreation) (int32, error) {
+	return 0, nil
+}
+
+// fakeLimits counts the submissions of every user without a window
+type fakeLimits struct {
+	counts map[int32]int64
+}
+
+func (l *fakeLimits) CountSubmission(_ context.Context, _, userId int32, _ time.Duration) (int64, error) {
+	l.counts[userId]++
+	return l.counts[userId], nil
+}
+
+func (l *fakeLimits) UncountSubmission(_ context.Context, _, userId int32) error {
+	l.counts[userId]--
+	return nil
+}
+
+type fakeStorage struct {
+	sources map[string]string
+	err     error
+}
+
+func (s *fakeStorage) UploadSource(_ context.Context, id int32, reader io.Reader) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+
+	key := fmt.Sprintf("solutions/%d/source", id)
+	s.sources[key] = string(data)
+	return key, nil
+}
+
+func (s *fakeStorage) DownloadSource(_ context.Context, key string) (io.ReadCloser, error) {
+	source, ok := s.sources[key]
+	if !ok {
+		return nil, pkg.Wrap(pkg.ErrNotFound, nil, "fakeStorage.DownloadSource", "no source")
+	}
+	return io.NopCloser(strings.NewReader(source)), nil
+}
+
+type fakeContests struct {
+	contests.UseCase
+}
+
+func (c fakeContests) GetContestProblem(context.Context, int32, int32) (*models.ContestProblem, error) {
+	return &models.ContestProblem{Languages: models.Languages{models.Cpp}}, nil
+}
+
+type fakeProblems struct {
+	problems.UseCase
+}
+
+func (p fakeProblems) GetProblemById(context.Context, int32) (*models.Problem, error) {
+	return &models.Problem{Type: models.ProblemStandard}, nil
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{legacy: make(map[int32]string), keys: make(map[int32]string)}
+}
+
+func TestMigrateSources(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		repo := newFakeRepository()
+		repo.legacy = map[int32]string{1: "a", 2: "bb", 3: "ccc"}
+		storage := &fakeStorage{sources: make(map[string]string)}
+
+		moved, err := usecase.MigrateSources(context.Background(), repo, storage, 2)
+		require.NoError(t, err)
+		require.Equal(t, 3, moved)
+		require.Empty(t, repo.legacy)
+		require.Equal(t, map[int32]string{1: "solutions/1/source", 2: "solutions/2/source", 3: "solutions/3/source"}, repo.keys)
+		require.Equal(t, "bb", storage.sources["solutions/2/source"])
+	})
+
+	t.Run("upload failed", func(t *testing.T) {
+		repo := newFakeRepository()
+		repo.legacy = map[int32]string{1: "a"}
+		storage := &fakeStorage{sources: make(map[string]string), err: errors.New("unavailable")}
+
+		moved, err := usecase.MigrateSources(context.Background(), repo, storage, 2)
+		require.Error(t, err)
+		require.Zero(t, moved)
+		require.Equal(t, map[int32]string{1: "a"}, repo.legacy, "kept in the database")
+		require.Empty(t, repo.keys)
+	})
+}
+
+func TestUseCase_ReadSource(t *testing.T) {
+	t.Parallel()
+
+	source := "int main() {}"
+	storage := &fakeStorage{sources: map[string]string{"solutions/1/source": source}}
+	uc := usecase.NewUseCase(nil, nil, nil, storage, nil, nil, nil, nil, 0, models.SubmitLimit{}, zap.NewNop())
+
+	for name, tc := range map[string]struct {
+		key  string
+		size int32
+		hash string
+		err  error
+	}{
+		"success":       {"solutions/1/source", int32(len(source)), models.SourceHash([]byte(source)), nil},
+		"shorter":       {"solutions/1/source", int32(len(source)) + 1, models.SourceHash([]byte(source)), pkg.ErrInternal},
+		"longer":        {"solutions/1/source", int32(len(source)) - 1, models.SourceHash([]byte(source)), pkg.ErrInternal},
+		"hash mismatch": {"solutions/1/source", int32(len(source)), models.SourceHash([]byte("other")), pkg.ErrInternal},
+		"missing":       {"solutions/2/source", int32(len(source)), models.SourceHash([]byte(source)), pkg.ErrNotFound},
+	} {
+		t.Run(name, func(t *testing.T) {
+			res, err := uc.ReadSource(context.Background(), tc.key, tc.size, tc.hash)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, source, res)
+		})
+	}
+}
+
+func TestUseCase_CreateSolution(t *testing.T) {
+	t.Parallel()
+
+	creation := func() *models.SolutionCreation {
+		return &models.SolutionCreation{
+			Solution:  "int main() {}",
+			ProblemId: 1,
+			ContestId: 1,
+			UserId:    1,
+			Language:  models.Cpp,
+			Unlimited: true,
+		}
+	}
+
+	t.Run("upload failed", func(t *testing.T) {
+		repo := newFakeRepository()
+		storage := &fakeStorage{sources: make(map[string]string), err: errors.New("unavailable")}
+		uc := usecase.NewUseCase(repo, nil, nil, storage, fakeProblems{}, fakeContests{}, nil, nil,
+			0, models.SubmitLimit{}, zap.NewNop())
+
+		_, err := uc.CreateSolution(context.Background(), creation())
+		require.Error(t, err)
+		require.Equal(t, []int32{7}, repo.deleted, "a solution without its source is dropped")
+		require.Empty(t, repo.keys)
+	})
+
+	t.Run("upload failed with a submission limit", func(t *testing.T) {
+		repo := newFakeRepository()
+		limits := &fakeLimits{counts: make(map[int32]int64)}
+		storage := &fakeStorage{sources: make(map[string]string), err: errors.New("unavailable")}
+		uc := usecase.NewUseCase(repo, limits, nil, storage, fakeProblems{}, fakeContests{}, nil, nil,
+			0, models.SubmitLimit{Count: 1, Window: time.Minute}, zap.NewNop())
+
+		for range 2 {
+			c := creation()
+			c.Unlimited = false
+
+			_, err := uc.CreateSolution(context.Background(), c)
+			require.NotErrorIs(t, err, pkg.ErrTooManyRequests, "a dropped solution does not use up the quota")
+		}
+		require.Zero(t, limits.counts[1])
+	})
+}
+
+func TestUseCase_RunSolution(t *testing.T) {
+	t.Parallel()
+
+	uc := usecase.NewUseCase(newFakeRepository(), nil, nil, nil, fakeProblems{}, fakeContests{}, nil, nil,
+		0, models.SubmitLimit{}, zap.NewNop())
+
+	problemId, contestId := int32(1), int32(1)
+
+	t.Run("no contest", func(t *testing.T) {
+		_, err := uc.RunSolution(context.Background(), &models.SolutionRun{
+			UserId:    1,
+			ProblemId: &problemId,
+			Language:  models.Cpp,
+		})
+		require.ErrorIs(t, err, pkg.ErrBadInput)
+	})
+
+	t.Run("language not allowed", func(t *testing.T) {
+		_, err := uc.RunSolution(context.Background(), &models.SolutionRun{
+			UserId:    2,
+			ContestId: &contestId,
+			ProblemId: &problemId,
+			Language:  models.Python,
+		})
+		require.ErrorIs(t, err, pkg.ErrBadInput)
+	})
+}
@@ -12,8 +12,15 @@ import (
 	contestsHandlers "github.com/Vyacheslav1557/tester/internal/contests/delivery/rest"
 	contestsRepository "github.com/Vyacheslav1557/tester/internal/contests/repository"
 	contestsUseCase "github.com/Vyacheslav1557/tester/internal/contests/usecase"
+	"github.com/Vyacheslav1557/tester/internal/languages"
+	languagesHandlers "github.com/Vyacheslav1557/tester/internal/languages/delivery/rest"
+	languagesUseCase "github.com/Vyacheslav1557/tester/internal/languages/usecase"
 	"github.com/Vyacheslav1557/tester/internal/middleware"
 	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/Vyacheslav1557/tester/internal/plagiarism"
+	plagiarismHandlers "github.com/Vyacheslav1557/tester/internal/plagiarism/delivery/rest"
+	plagiarismRepository "github.com/Vyacheslav1557/tester/internal/plagiarism/repository"
+	plagiarismUseCase "github.com/Vyacheslav1557/tester/internal/plagiarism/usecase"
 	"github.com/Vyacheslav1557/tester/internal/problems"
 	problemsHandlers "github.com/Vyacheslav1557/tester/internal/problems/delivery/rest"
 	problemsRepository "github.com/Vyacheslav1557/tester/internal/problems/repository"
@@ -38,10 +45,13 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 )
 
 func main() {
+	tester.SandboxInit()
+
 	var cfg config.Config
 	err := cleanenv.ReadConfig(".env", &cfg)
 	if err != nil {
@@ -121,20 +131,75 @@ func main() {
 	problemsRepo := problemsRepository.NewRepository(db)
 	s3Repo := problemsRepository.NewS3Repository(s3Client, "tester-problems-archives")
 
-	problemsUC := problemsUseCase.NewUseCase(problemsRepo, pandocClient, s3Repo, cfg.CacheDir)
-
-	contestsRepo := contestsRepository.NewRepository(db)
-	contestsUC := contestsUseCase.NewContestUseCase(contestsRepo)
-
 	cli, err := client.NewClientWithOpts(client.FromEnv)
 	if err != nil {
 		panic(fmt.Errorf("failed to create docker client: %v", err))
 	}
 
-	t := tester.NewTester(cfg.CacheDir, tester.NewDockerExecutor(cli), 2)
+	if cfg.LanguagesFile != "" {
+		registry, err := tester.LoadRegistry(cfg.LanguagesFile)
+		if err != nil {
+			logger.Fatal(fmt.Sprintf("error loading languages: %v", err))
+		}
+		tester.SetRegistry(registry)
+	}
+
+	var executor tester.Executor
+	switch cfg.Executor {
+	case "docker":
+		dockerExecutor := tester.NewDockerExecutor(cli, filepath.Join(cfg.CacheDir, "warm"))
+		defer dockerExecutor.Close(context.Background())
+
+		err = dockerExecutor.Warm(context.Background(), tester.Languages().List())
+		if err != nil {
+			logger.Error(fmt.Sprintf("error warming containers: %v", err))
+		}
+		for _, lang := range tester.Languages().List() {
+			if dockerExecutor.Pooled(lang) {
+				logger.Info(fmt.Sprintf("%s runs in warm containers", lang.Name))
+			} else {
+				logger.Info(fmt.Sprintf("%s runs in a container per run", lang.Name))
+			}
+		}
+		executor = dockerExecutor
+	case "native":
+		executor, err = tester.NewNativeExecutor(cfg.SandboxRoot, cfg.SandboxCgroup)
+		if err != nil {
+			logger.Fatal(fmt.Sprintf("error creating native executor: %v", err))
+		}
+	default:
+		logger.Fatal(fmt.Sprintf("unknown executor %q", cfg.Executor))
+	}
+
+	t := tester.NewTester(cfg.CacheDir, executor, 2, 1)
+
+	problemsUC := problemsUseCase.NewUseCase(problemsRepo, pandocClient, s3Repo, t, cfg.CacheDir)
+
+	contestsRepo := contestsRepository.NewRepository(db)
+	contestsUC := contestsUseCase.NewContestUseCase(contestsRepo, problemsUC)
+
+	languagesUC := languagesUseCase.NewUseCase(tester.Languages())
 
 	solutionsRepo := solutionsRepository.NewRepository(db)
-	solutionsUC := solutionsUseCase.NewUseCase(solutionsRepo, problemsUC, np, t)
+	limitsRepo := solutionsRepository.NewValkeyRepository(vk)
+	// the sources and the zips of solutions share the bucket
+	solutionsS3Repo := solutionsRepository.NewS3Repository(s3Client, "tester-solutions-archives")
+	solutionsUC := solutionsUseCase.NewUseCase(
+		solutionsRepo,
+		limitsRepo,
+		solutionsS3Repo,
+		solutionsS3Repo,
+		problemsUC,
+		contestsUC,
+		np,
+		t,
+		cfg.JudgeRetries,
+		models.SubmitLimit{Count: cfg.SubmitLimit, Window: cfg.SubmitWindow},
+		logger,
+	)
+
+	plagiarismRepo := plagiarismRepository.NewRepository(db)
+	plagiarismUC := plagiarismUseCase.NewUseCase(plagiarismRepo, solutionsUC, contestsUC)
 
 	if err := os.MkdirAll(cfg.CacheDir, 0700); err != nil {
 		panic(fmt.Errorf("failed to create cache dir: %v", err))
@@ -150,6 +215,8 @@ func main() {
 		contests.ContestsHandlers
 		problems.ProblemsHandlers
 		solutions.SolutionsHandlers
+		languages.LanguagesHandlers
+		plagiarism.PlagiarismHandlers
 	}
 
 	merged := MergedHandlers{
@@ -158,6 +225,8 @@ func main() {
 		contestsHandlers.NewHandlers(problemsUC, contestsUC),
 		problemsHandlers.NewHandlers(problemsUC),
 		solutionsHandlers.NewHandlers(solutionsUC, problemsUC, contestsUC),
+		languagesHandlers.NewHandlers(languagesUC),
+		plagiarismHandlers.NewHandlers(plagiarismUC),
 	}
 
 	testerv1.RegisterHandlersWithOptions(server, merged, testerv1.FiberServerOptions{
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE solutions
+    ADD COLUMN IF NOT EXISTS pretest boolean NOT NULL DEFAULT false;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE solutions
+    DROP COLUMN IF EXISTS pretest;
+-- +goose StatementEnd
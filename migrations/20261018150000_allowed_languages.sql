@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE contests
+    ADD COLUMN IF NOT EXISTS languages jsonb NOT NULL DEFAULT '[]';
+ALTER TABLE contest_problem
+    ADD COLUMN IF NOT EXISTS languages jsonb NOT NULL DEFAULT '[]';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE contest_problem
+    DROP COLUMN IF EXISTS languages;
+ALTER TABLE contests
+    DROP COLUMN IF EXISTS languages;
+-- +goose StatementEnd
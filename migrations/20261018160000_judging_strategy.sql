@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE problems
+    ADD COLUMN IF NOT EXISTS strategy smallint NOT NULL DEFAULT 1;
+ALTER TABLE contests
+    ADD COLUMN IF NOT EXISTS strategy smallint NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE contests
+    DROP COLUMN IF EXISTS strategy;
+ALTER TABLE problems
+    DROP COLUMN IF EXISTS strategy;
+-- +goose StatementEnd
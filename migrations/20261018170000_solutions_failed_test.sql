@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE solutions
+    ADD COLUMN IF NOT EXISTS failed_test integer NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE solutions
+    DROP COLUMN IF EXISTS failed_test;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE solutions
+    ADD COLUMN IF NOT EXISTS diagnostics jsonb NOT NULL DEFAULT '{}';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE solutions
+    DROP COLUMN IF EXISTS diagnostics;
+-- +goose StatementEnd
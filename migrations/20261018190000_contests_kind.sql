@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE contests
+    ADD COLUMN IF NOT EXISTS kind smallint NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE contests
+    DROP COLUMN IF EXISTS kind;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE contests
+    ADD COLUMN IF NOT EXISTS submit_limit  integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS submit_window integer NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE contests
+    DROP COLUMN IF EXISTS submit_limit,
+    DROP COLUMN IF EXISTS submit_window;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS plagiarism_pairs
+(
+    contest_id integer          NOT NULL REFERENCES contests (id) ON DELETE CASCADE,
+    problem_id integer          NOT NULL REFERENCES problems (id) ON DELETE CASCADE,
+    solution_a integer          NOT NULL REFERENCES solutions (id) ON DELETE CASCADE,
+    solution_b integer          NOT NULL REFERENCES solutions (id) ON DELETE CASCADE,
+    similarity double precision NOT NULL,
+    created_at timestamptz      NOT NULL DEFAULT now(),
+    PRIMARY KEY (contest_id, problem_id, solution_a, solution_b)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS plagiarism_pairs;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE solutions
+    ADD COLUMN IF NOT EXISTS archive_key varchar(255);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE solutions
+    DROP COLUMN IF EXISTS archive_key;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE solutions
+    ADD COLUMN IF NOT EXISTS source_key  varchar(255),
+    ADD COLUMN IF NOT EXISTS source_hash varchar(64) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS source_size integer     NOT NULL DEFAULT 0,
+    ALTER COLUMN solution DROP NOT NULL;
+
+-- the sources still in the table are moved to the object storage by cmd/migrate-sources
+UPDATE solutions
+SET source_hash = encode(sha256(convert_to(solution, 'UTF8')), 'hex'),
+    source_size = octet_length(solution)
+WHERE solution IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- the sources already moved to the object storage are not brought back
+UPDATE solutions
+SET solution = ''
+WHERE solution IS NULL;
+
+ALTER TABLE solutions
+    DROP COLUMN IF EXISTS source_key,
+    DROP COLUMN IF EXISTS source_hash,
+    DROP COLUMN IF EXISTS source_size,
+    ALTER COLUMN solution SET NOT NULL;
+-- +goose StatementEnd
@@ -0,0 +1,284 @@
+// Package diff compares texts line by line with the Myers algorithm
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Op int
+
+const (
+	Equal  Op = iota // the line is in both texts
+	Delete           // the line is only in the old text
+	Insert           // the line is only in the new text
+)
+
+// Edit is a line of either text, Old and New are 0-based line
+// numbers, -1 for the text the line is not in
+type Edit struct {
+	Op   Op
+	Old  int
+	New  int
+	Text string
+}
+
+// SplitLines splits the text into lines without the line breaks,
+// a trailing line break does not make an empty last line
+func SplitLines(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// Lines returns the shortest edit script turning a into b, lines are
+// matched by their keys, e.g. the lines themselves or without whitespace
+func Lines(a, b []string, key func(string) string) []Edit {
+	ka, kb := make([]string, len(a)), make([]string, len(b))
+	for i, line := range a {
+		ka[i] = key(line)
+	}
+	for i, line := range b {
+		kb[i] = key(line)
+	}
+
+	var edits []Edit
+	x, y := 0, 0
+	for _, snake := range myers(ka, kb) {
+		for ; x < snake.x; x++ {
+			edits = append(edits, Edit{Op: Delete, Old: x, New: -1, Text: a[x]})
+		}
+		for ; y < snake.y; y++ {
+			edits = append(edits, Edit{Op: Insert, Old: -1, New: y, Text: b[y]})
+		}
+		for i := 0; i < snake.n; i, x, y = i+1, x+1, y+1 {
+			edits = append(edits, Edit{Op: Equal, Old: x, New: y, Text: b[y]})
+		}
+	}
+
+	return edits
+}
+
+// Identity compares lines as they are
+func Identity(line string) string {
+	return line
+}
+
+// IgnoreWhitespace compares lines with all whitespace removed
+func IgnoreWhitespace(line string) string {
+	return strings.Join(strings.Fields(line), "")
+}
+
+// maxEdits bounds the work on texts having nothing in common,
+// the rest of such texts is reported as replaced entirely
+const maxEdits = 1024
+
+// snake is a run of n equal lines starting at a[x] and b[y]
+type snake struct {
+	x, y, n int
+}
+
+// myers returns the common runs of a and b in order, the last one is always
+// an empty run at the ends of both, so that the trailing changes are not lost
+func myers(a, b []string) []snake {
+	n, m := len(a), len(b)
+
+	// the common prefix and suffix need no search
+	prefix := 0
+	for prefix < n && prefix < m && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < n-prefix && suffix < m-prefix && a[n-1-suffix] == b[m-1-suffix] {
+		suffix++
+	}
+
+	var snakes []snake
+	if prefix > 0 {
+		snakes = append(snakes, snake{n: prefix})
+	}
+
+	for _, s := range search(a[prefix:n-suffix], b[prefix:m-suffix]) {
+		snakes = append(snakes, snake{x: s.x + prefix, y: s.y + prefix, n: s.n})
+	}
+
+	if suffix > 0 {
+		snakes = append(snakes, snake{x: n - suffix, y: m - suffix, n: suffix})
+	}
+
+	return append(snakes, snake{x: n, y: m})
+}
+
+// search finds the common runs of a and b, none if it gives up after maxEdits
+func search(a, b []string) []snake {
+	n, m := len(a), len(b)
+	maxD := min(n+m, maxEdits)
+
+	// v[k] is the furthest x on the diagonal k = x - y,
+	// trace[d] keeps the diagonals -d..d as they were before the step d
+	v := make(map[int]int, 2*maxD+3)
+	var trace [][]int
+
+	found := false
+	d := 0
+	for ; d <= maxD && !found; d++ {
+		row := make([]int, 2*d+1)
+		for k := -d; k <= d; k++ {
+			row[k+d] = v[k]
+		}
+		trace = append(trace, row)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[k-1] < v[k+1]) {
+				x = v[k+1]
+			} else {
+				x = v[k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[k] = x
+
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		return nil
+	}
+	d-- // the step that has reached the end
+
+	// walk the trace back from the end and collect the diagonals
+	var snakes []snake
+	x, y := n, m
+	for ; d > 0; d-- {
+		row := trace[d]
+		at := func(k int) int { return row[k+d] }
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		// the diagonal run follows the single insertion or deletion
+		startX, startY := prevX, prevY
+		if prevK == k+1 {
+			startY++
+		} else {
+			startX++
+		}
+
+		if x > startX {
+			snakes = append(snakes, snake{x: startX, y: startY, n: x - startX})
+		}
+		x, y = prevX, prevY
+	}
+
+	if x > 0 {
+		snakes = append(snakes, snake{x: 0, y: 0, n: x})
+	}
+
+	// the snakes have been collected backwards
+	for i, j := 0, len(snakes)-1; i < j; i, j = i+1, j-1 {
+		snakes[i], snakes[j] = snakes[j], snakes[i]
+	}
+
+	return snakes
+}
+
+// Unified formats the edit script as a unified diff with the given number
+// of context lines around the changes, it is empty if nothing has changed
+func Unified(edits []Edit, oldName, newName string, context int) string {
+	var changed []int
+	for i, e := range edits {
+		if e.Op != Equal {
+			changed = append(changed, i)
+		}
+	}
+	if len(changed) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+
+	// changes closer than twice the context share a hunk
+	for i := 0; i < len(changed); {
+		j := i
+		for j+1 < len(changed) && changed[j+1]-changed[j] <= 2*context+1 {
+			j++
+		}
+
+		start := max(0, changed[i]-context)
+		end := min(len(edits), changed[j]+context+1)
+		writeHunk(&sb, edits, start, end)
+
+		i = j + 1
+	}
+
+	return sb.String()
+}
+
+// writeHunk writes edits[start:end], the lines before start are counted for the header
+func writeHunk(sb *strings.Builder, edits []Edit, start, end int) {
+	oldStart, newStart := 0, 0
+	for _, e := range edits[:start] {
+		if e.Op != Insert {
+			oldStart++
+		}
+		if e.Op != Delete {
+			newStart++
+		}
+	}
+
+	oldLen, newLen := 0, 0
+	for _, e := range edits[start:end] {
+		if e.Op != Insert {
+			oldLen++
+		}
+		if e.Op != Delete {
+			newLen++
+		}
+	}
+
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(oldStart, oldLen), hunkRange(newStart, newLen))
+
+	for _, e := range edits[start:end] {
+		switch e.Op {
+		case Equal:
+			sb.WriteString(" ")
+		case Delete:
+			sb.WriteString("-")
+		case Insert:
+			sb.WriteString("+")
+		}
+		sb.WriteString(e.Text)
+		sb.WriteString("\n")
+	}
+}
+
+// hunkRange is 1-based, an empty range starts at the line before it as diff(1) does
+func hunkRange(before, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	if n == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, n)
+}
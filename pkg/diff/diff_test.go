@@ -0,0 +1,179 @@
+package diff_test
+
+import (
+	"github.com/Vyacheslav1557/tester/pkg/diff"
+	"github.com/stretchr/testify/require"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// apply rebuilds both texts from the edit script
+func apply(edits []diff.Edit) (old, new []string) {
+	for _, e := range edits {
+		if e.Op != diff.Insert {
+			old = append(old, e.Text)
+		}
+		if e.Op != diff.Delete {
+			new = append(new, e.Text)
+		}
+	}
+	return old, new
+}
+
+func changes(edits []diff.Edit) int {
+	n := 0
+	for _, e := range edits {
+		if e.Op != diff.Equal {
+			n++
+		}
+	}
+	return n
+}
+
+func lcs(a, b []string) int {
+	dp := make([][]int, len(a)+1)
+	for i := range dp {
+		dp[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else {
+				dp[i][j] = max(dp[i+1][j], dp[i][j+1])
+			}
+		}
+	}
+
+	return dp[0][0]
+}
+
+func TestLines(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		a, b    string
+		changes int
+	}{
+		{name: "equal", a: "a\nb\nc\n", b: "a\nb\nc\n", changes: 0},
+		{name: "empty", a: "", b: "", changes: 0},
+		{name: "added", a: "", b: "a\nb", changes: 2},
+		{name: "removed", a: "a\nb", b: "", changes: 2},
+		{name: "middle", a: "a\nb\nc", b: "a\nx\nc", changes: 2},
+		{name: "moved", a: "a\nb\nc\nd", b: "b\nc\nd\na", changes: 2},
+		{name: "scattered", a: "a\nb\nc\nd\ne\nf", b: "x\nb\nc\ny\ne\nz", changes: 6},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			a, b := diff.SplitLines(tc.a), diff.SplitLines(tc.b)
+
+			edits := diff.Lines(a, b, diff.Identity)
+
+			old, new := apply(edits)
+			require.Equal(t, a, old)
+			require.Equal(t, b, new)
+			require.Equal(t, tc.changes, changes(edits))
+		})
+	}
+}
+
+func TestLines_Random(t *testing.T) {
+	t.Parallel()
+
+	r := rand.New(rand.NewSource(1))
+	random := func() []string {
+		lines := make([]string, r.Intn(50))
+		for i := range lines {
+			lines[i] = string(rune('a' + r.Intn(4)))
+		}
+		return lines
+	}
+
+	for i := 0; i < 200; i++ {
+		a, b := random(), random()
+
+		edits := diff.Lines(a, b, diff.Identity)
+
+		// the script is the shortest one
+		require.Equal(t, len(a)+len(b)-2*lcs(a, b), changes(edits))
+
+		old, new := apply(edits)
+		require.Equal(t, len(a), len(old))
+		require.Equal(t, len(b), len(new))
+		if len(a) > 0 {
+			require.Equal(t, a, old)
+		}
+		if len(b) > 0 {
+			require.Equal(t, b, new)
+		}
+	}
+}
+
+func TestLines_IgnoreWhitespace(t *testing.T) {
+	t.Parallel()
+
+	a := diff.SplitLines("int main() {\n\treturn 0;\n}")
+	b := diff.SplitLines("int main(){\n    return 0;\n}\n")
+
+	require.Zero(t, changes(diff.Lines(a, b, diff.IgnoreWhitespace)))
+	require.Equal(t, 4, changes(diff.Lines(a, b, diff.Identity)))
+}
+
+func TestLines_Unrelated(t *testing.T) {
+	t.Parallel()
+
+	var a, b []string
+	for i := 0; i < 3000; i++ {
+		a = append(a, "a"+strings.Repeat("x", i%7))
+		b = append(b, "b"+strings.Repeat("y", i%5))
+	}
+	a = append([]string{"head"}, append(a, "tail")...)
+	b = append([]string{"head"}, append(b, "tail")...)
+
+	edits := diff.Lines(a, b, diff.Identity)
+
+	old, new := apply(edits)
+	require.Equal(t, a, old)
+	require.Equal(t, b, new)
+	require.Equal(t, diff.Equal, edits[0].Op)
+	require.Equal(t, diff.Equal, edits[len(edits)-1].Op)
+}
+
+func TestUnified(t *testing.T) {
+	t.Parallel()
+
+	var a []string
+	for i := 1; i <= 12; i++ {
+		a = append(a, strings.Repeat("x", i))
+	}
+	b := append([]string{"first"}, a...)
+	b[3] = "changed"
+	b = append(b[:11], b[12:]...)
+
+	expected := `--- a
++++ b
+@@ -1,6 +1,7 @@
++first
+ x
+ xx
+-xxx
++changed
+ xxxx
+ xxxxx
+ xxxxxx
+@@ -8,5 +9,4 @@
+ xxxxxxxx
+ xxxxxxxxx
+ xxxxxxxxxx
+-xxxxxxxxxxx
+ xxxxxxxxxxxx
+`
+
+	require.Equal(t, expected, diff.Unified(diff.Lines(a, b, diff.Identity), "a", "b", 3))
+	require.Empty(t, diff.Unified(diff.Lines(a, a, diff.Identity), "a", "a", 3))
+	require.Equal(t, "--- a\n+++ b\n@@ -1 +0,0 @@\n-x\n", diff.Unified(diff.Lines(a[:1], nil, diff.Identity), "a", "b", 3))
+}
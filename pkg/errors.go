@@ -17,6 +17,8 @@ var (
 	ErrNotFound        = errors.New("not found")
 	ErrBadInput        = errors.New("bad input")
 	ErrInternal        = errors.New("internal")
+	ErrTooManyRequests = errors.New("too many requests")
+	ErrConflict        = errors.New("conflict")
 )
 
 type CustomError struct {
@@ -66,6 +68,10 @@ func ToREST(err error) int {
 		return http.StatusInternalServerError
 	case errors.Is(err, NoPermission):
 		return http.StatusForbidden
+	case errors.Is(err, ErrTooManyRequests):
+		return http.StatusTooManyRequests
+	case errors.Is(err, ErrConflict):
+		return http.StatusConflict
 	}
 
 	return http.StatusInternalServerError
@@ -0,0 +1,129 @@
+package plagiarism_test
+
+import (
+	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/Vyacheslav1557/tester/pkg/plagiarism"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const original = `#include <iostream>
+using namespace std;
+
+// counts the pairs with the given sum
+int main() {
+    int n, s;
+    cin >> n >> s;
+    int a[100];
+    for (int i = 0; i < n; i++) cin >> a[i];
+    int cnt = 0;
+    for (int i = 0; i < n; i++)
+        for (int j = i + 1; j < n; j++)
+            if (a[i] + a[j] == s) cnt++;
+    cout << cnt << endl;
+    return 0;
+}
+`
+
+// the same code with renamed variables, other comments and formatting
+const renamed = `#include <iostream>
+using namespace std;
+/* my own solution */
+int main()
+{
+    int size, target;
+    cin >> size >> target;
+    int values[100];
+    for (int k = 0; k < size; k++)
+        cin >> values[k];
+    int answer = 0;
+    for (int x = 0; x < size; x++)
+        for (int y = x + 1; y < size; y++)
+            if (values[x] + values[y] == target) answer++; // found one
+    cout << answer << endl;
+    return 0;
+}
+`
+
+const different = `#include <bits/stdc++.h>
+using namespace std;
+
+int main() {
+    long long n;
+    cin >> n;
+    vector<long long> dp(n + 1, 0);
+    dp[0] = 1;
+    while (n > 0) {
+        n /= 2;
+        dp[n] += dp[n] * 3 - 1;
+    }
+    printf("%lld\n", dp[0]);
+}
+`
+
+func TestTokenize(t *testing.T) {
+	t.Parallel()
+
+	t.Run("comments and names", func(t *testing.T) {
+		tokens := plagiarism.Tokenize(models.Golang, "// comment\nx := foo(1, \"s\") /* more */ + y")
+		require.Equal(t, []string{"I", ":", "=", "I", "(", "N", ",", "S", ")", "+", "I"}, tokens)
+	})
+
+	t.Run("keywords", func(t *testing.T) {
+		tokens := plagiarism.Tokenize(models.Python, "def f(x):  # comment\n    return 'a\\'b'")
+		require.Equal(t, []string{"def", "I", "(", "I", ")", ":", "return", "S"}, tokens)
+	})
+
+	t.Run("renamed", func(t *testing.T) {
+		require.Equal(t,
+			plagiarism.Tokenize(models.Cpp, original),
+			plagiarism.Tokenize(models.Cpp, renamed),
+		)
+	})
+}
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	doc := func(id int32, source string) plagiarism.Document {
+		return plagiarism.Document{
+			Id:           id,
+			Fingerprints: plagiarism.Fingerprint(plagiarism.Tokenize(models.Cpp, source)),
+		}
+	}
+
+	pairs := plagiarism.Compare([]plagiarism.Document{
+		doc(3, renamed),
+		doc(1, original),
+		doc(2, different),
+	}, 0.5)
+
+	require.Len(t, pairs, 1)
+	require.Equal(t, int32(1), pairs[0].A)
+	require.Equal(t, int32(3), pairs[0].B)
+	require.InDelta(t, 1, pairs[0].Similarity, 1e-9)
+}
+
+func TestCompare_Common(t *testing.T) {
+	t.Parallel()
+
+	// everybody reads the input the same way, only that is shared
+	const template = "int n; cin >> n; vector<int> a(n); for (auto &x : a) cin >> x;\n"
+
+	var docs []plagiarism.Document
+	for i, body := range []string{
+		"sort(a.begin(), a.end()); cout << a[0];",
+		"long long s = 0; for (int v : a) s += v; cout << s;",
+		"cout << *max_element(a.begin(), a.end()) - 1;",
+		"while (n--) if (a[n] % 2 == 0) cout << a[n] * 2 << ' ';",
+		"int best = 0; for (int i = 1; i < n; i++) best = max(best, a[i] - a[i - 1]); cout << best;",
+		"map<int, int> cnt; for (int v : a) cnt[v]++; cout << cnt.size() << endl;",
+	} {
+		docs = append(docs, plagiarism.Document{
+			Id:           int32(i + 1),
+			Fingerprints: plagiarism.Fingerprint(plagiarism.Tokenize(models.Cpp, template+body)),
+		})
+	}
+
+	require.Empty(t, plagiarism.Compare(docs, 0.5))
+}
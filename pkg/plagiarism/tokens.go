@@ -0,0 +1,150 @@
+// Package plagiarism finds similar solutions with winnowed fingerprints of their tokens
+package plagiarism
+
+import (
+	"github.com/Vyacheslav1557/tester/internal/models"
+	"strings"
+	"unicode"
+)
+
+// syntax is what the tokenizer needs to know about a language
+type syntax struct {
+	lineComment  []string
+	blockComment [2]string
+	quotes       string // string and char literal delimiters
+	keywords     map[string]bool
+}
+
+func words(s string) map[string]bool {
+	res := make(map[string]bool)
+	for _, w := range strings.Fields(s) {
+		res[w] = true
+	}
+	return res
+}
+
+var syntaxes = map[models.LanguageName]syntax{
+	models.Golang: {
+		lineComment:  []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'`",
+		keywords: words(`break case chan const continue default defer else fallthrough for func go goto if
+			import interface map package range return select struct switch type var`),
+	},
+	models.Cpp: {
+		lineComment:  []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'",
+		keywords: words(`auto bool break case char class const continue default delete do double else enum
+			float for if int long namespace new operator private public return short signed sizeof static
+			struct switch template this typedef typename unsigned using void while`),
+	},
+	models.Python: {
+		lineComment: []string{"#"},
+		quotes:      "\"'",
+		keywords: words(`and as assert break class continue def del elif else except finally for from global
+			if import in is lambda nonlocal not or pass raise return try while with yield`),
+	},
+	models.Java: {
+		lineComment:  []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'",
+		keywords: words(`abstract boolean break byte case catch char class continue default do double else
+			extends final finally float for if implements import instanceof int interface long new package
+			private protected public return short static super switch this throw throws try void while`),
+	},
+	models.Kotlin: {
+		lineComment:  []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'",
+		keywords: words(`as break class continue do else false for fun if in interface is null object package
+			return super this throw true try typealias val var when while`),
+	},
+}
+
+// generic is used for the languages unknown to the tokenizer
+var generic = syntax{
+	lineComment:  []string{"//", "#"},
+	blockComment: [2]string{"/*", "*/"},
+	quotes:       "\"'",
+}
+
+const (
+	identToken  = "I"
+	numberToken = "N"
+	stringToken = "S"
+)
+
+// Tokenize turns the source into tokens which survive renaming and reformatting:
+// comments and whitespace are dropped, identifiers, numbers and literals are
+// replaced with placeholders, keywords and punctuation are kept as they are
+func Tokenize(lang models.LanguageName, source string) []string {
+	syn, ok := syntaxes[lang]
+	if !ok {
+		syn = generic
+	}
+
+	src := []rune(source)
+	hasPrefix := func(i int, prefix string) bool {
+		return prefix != "" && strings.HasPrefix(string(src[i:min(len(src), i+len(prefix))]), prefix)
+	}
+
+	var tokens []string
+	for i := 0; i < len(src); {
+		c := src[i]
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case hasAnyPrefix(hasPrefix, i, syn.lineComment):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case hasPrefix(i, syn.blockComment[0]):
+			i += len([]rune(syn.blockComment[0]))
+			for i < len(src) && !hasPrefix(i, syn.blockComment[1]) {
+				i++
+			}
+			i += len([]rune(syn.blockComment[1]))
+		case strings.ContainsRune(syn.quotes, c):
+			i++
+			for i < len(src) && src[i] != c {
+				if src[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i++
+			tokens = append(tokens, stringToken)
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(src) && (unicode.IsLetter(src[i]) || unicode.IsDigit(src[i]) || src[i] == '_') {
+				i++
+			}
+			if word := string(src[start:i]); syn.keywords[word] {
+				tokens = append(tokens, word)
+			} else {
+				tokens = append(tokens, identToken)
+			}
+		case unicode.IsDigit(c):
+			for i < len(src) && (unicode.IsLetter(src[i]) || unicode.IsDigit(src[i]) || src[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, numberToken)
+		default:
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+
+	return tokens
+}
+
+func hasAnyPrefix(hasPrefix func(int, string) bool, i int, prefixes []string) bool {
+	for _, p := range prefixes {
+		if hasPrefix(i, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package plagiarism
+
+import (
+	"hash/fnv"
+	"sort"
+)
+
+const (
+	kgram  = 5 // tokens hashed together, shorter matches are noise
+	window = 4 // every run of kgram+window-1 tokens keeps at least one fingerprint
+
+	// fingerprints found in more than this share of the documents are
+	// the code everybody has, e.g. reading the input, and are not counted
+	commonShare = 0.5
+	commonDocs  = 5 // fewer documents tell nothing about what is common
+)
+
+// Fingerprints is a set of the winnowed k-gram hashes of a document
+type Fingerprints map[uint64]struct{}
+
+// Fingerprint hashes every k-gram of the tokens and keeps the minimal hash
+// of every window of them, as MOSS does. Equal code fragments of at least
+// kgram+window-1 tokens always share a fingerprint.
+func Fingerprint(tokens []string) Fingerprints {
+	res := make(Fingerprints)
+
+	if len(tokens) < kgram {
+		if len(tokens) > 0 {
+			res[hash(tokens)] = struct{}{}
+		}
+		return res
+	}
+
+	hashes := make([]uint64, len(tokens)-kgram+1)
+	for i := range hashes {
+		hashes[i] = hash(tokens[i : i+kgram])
+	}
+
+	if len(hashes) <= window {
+		res[minimal(hashes)] = struct{}{}
+		return res
+	}
+
+	for i := 0; i+window <= len(hashes); i++ {
+		res[minimal(hashes[i:i+window])] = struct{}{}
+	}
+
+	return res
+}
+
+func hash(tokens []string) uint64 {
+	h := fnv.New64a()
+	for _, t := range tokens {
+		h.Write([]byte(t))
+		h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
+
+func minimal(hashes []uint64) uint64 {
+	res := hashes[0]
+	for _, h := range hashes[1:] {
+		res = min(res, h)
+	}
+	return res
+}
+
+// Document is a fingerprinted solution
+type Document struct {
+	Id           int32
+	Fingerprints Fingerprints
+}
+
+// Pair is the similarity of two documents, A < B
+type Pair struct {
+	A, B       int32
+	Similarity float64 // shared fingerprints over the average number of them, 0 to 1
+}
+
+// Compare scores every pair of the documents and returns the ones at least
+// as similar as threshold, the most similar first
+func Compare(docs []Document, threshold float64) []Pair {
+	common := commonFingerprints(docs)
+
+	filtered := make([]Fingerprints, len(docs))
+	for i, doc := range docs {
+		filtered[i] = make(Fingerprints, len(doc.Fingerprints))
+		for f := range doc.Fingerprints {
+			if _, ok := common[f]; !ok {
+				filtered[i][f] = struct{}{}
+			}
+		}
+	}
+
+	var pairs []Pair
+	for i := range docs {
+		for j := i + 1; j < len(docs); j++ {
+			similarity := dice(filtered[i], filtered[j])
+			if similarity < threshold || similarity == 0 {
+				continue
+			}
+
+			a, b := docs[i].Id, docs[j].Id
+			if a > b {
+				a, b = b, a
+			}
+			pairs = append(pairs, Pair{A: a, B: b, Similarity: similarity})
+		}
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Similarity != pairs[j].Similarity {
+			return pairs[i].Similarity > pairs[j].Similarity
+		}
+		if pairs[i].A != pairs[j].A {
+			return pairs[i].A < pairs[j].A
+		}
+		return pairs[i].B < pairs[j].B
+	})
+
+	return pairs
+}
+
+// commonFingerprints returns the fingerprints of more than commonShare of the documents
+func commonFingerprints(docs []Document) Fingerprints {
+	common := make(Fingerprints)
+	if len(docs) < commonDocs {
+		return common
+	}
+
+	counts := make(map[uint64]int)
+	for _, doc := range docs {
+		for f := range doc.Fingerprints {
+			counts[f]++
+		}
+	}
+
+	for f, n := range counts {
+		if float64(n) > commonShare*float64(len(docs)) {
+			common[f] = struct{}{}
+		}
+	}
+
+	return common
+}
+
+func dice(a, b Fingerprints) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+
+	shared := 0
+	for f := range a {
+		if _, ok := b[f]; ok {
+			shared++
+		}
+	}
+
+	return 2 * float64(shared) / float64(len(a)+len(b))
+}
@@ -0,0 +1,231 @@
+package tester
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ArchiveLimits bounds what a zip archive is allowed to expand to.
+type ArchiveLimits struct {
+	MaxFiles            int   // maximum number of entries, directories included
+	MaxTotalSize        int64 // maximum sum of uncompressed sizes in bytes
+	MaxCompressionRatio int64 // maximum uncompressed/compressed ratio of a single entry
+}
+
+var DefaultArchiveLimits = ArchiveLimits{
+	MaxFiles:            10000,
+	MaxTotalSize:        2 * 1024 * 1024 * 1024, // 2 GB
+	MaxCompressionRatio: 1000,
+}
+
+// entries smaller than this are not checked against MaxCompressionRatio,
+// tiny files full of repeated characters compress extremely well
+const compressionRatioThreshold = 1024 * 1024 // 1 MB
+
+// ArchiveReader is a zip reader which rejects entries that could escape
+// the destination directory and refuses to expand beyond ArchiveLimits.
+type ArchiveReader struct {
+	reader *zip.Reader
+	limits ArchiveLimits
+	read   int64 // uncompressed bytes read so far over all entries
+}
+
+func NewArchiveReader(r io.ReaderAt, size int64, limits ArchiveLimits) (*ArchiveReader, error) {
+	const op = "NewArchiveReader"
+
+	reader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrBadInput, err, op, "failed to open zip")
+	}
+
+	if len(reader.File) > limits.MaxFiles {
+		return nil, pkg.Wrap(pkg.ErrBadInput, nil, op,
+			fmt.Sprintf("archive has %d entries, limit is %d", len(reader.File), limits.MaxFiles))
+	}
+
+	var total uint64
+	for _, file := range reader.File {
+		if err := checkArchiveEntry(file, limits); err != nil {
+			return nil, err
+		}
+
+		total += file.UncompressedSize64
+		if total > uint64(limits.MaxTotalSize) {
+			return nil, pkg.Wrap(pkg.ErrBadInput, nil, op,
+				fmt.Sprintf("archive uncompressed size exceeds limit of %d bytes", limits.MaxTotalSize))
+		}
+	}
+
+	return &ArchiveReader{
+		reader: reader,
+		limits: limits,
+	}, nil
+}
+
+func checkArchiveEntry(file *zip.File, limits ArchiveLimits) error {
+	const op = "checkArchiveEntry"
+
+	name := file.Name
+
+	if name == "" || strings.ContainsRune(name, 0) {
+		return pkg.Wrap(pkg.ErrBadInput, nil, op, fmt.Sprintf("archive entry %q has invalid name", name))
+	}
+
+	slashed := strings.ReplaceAll(name, `\`, "/")
+	if strings.HasPrefix(slashed, "/") || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return pkg.Wrap(pkg.ErrBadInput, nil, op, fmt.Sprintf("archive entry %q has absolute path", name))
+	}
+
+	for _, part := range strings.Split(slashed, "/") {
+		if part == ".." {
+			return pkg.Wrap(pkg.ErrBadInput, nil, op,
+				fmt.Sprintf("archive entry %q escapes destination directory", name))
+		}
+	}
+
+	mode := file.Mode()
+	if mode&os.ModeSymlink != 0 {
+		return pkg.Wrap(pkg.ErrBadInput, nil, op, fmt.Sprintf("archive entry %q is a symlink", name))
+	}
+	if mode.Type()&^os.ModeDir != 0 {
+		return pkg.Wrap(pkg.ErrBadInput, nil, op, fmt.Sprintf("archive entry %q is not a regular file", name))
+	}
+
+	if file.UncompressedSize64 > compressionRatioThreshold {
+		if file.CompressedSize64 == 0 ||
+			file.UncompressedSize64/file.CompressedSize64 > uint64(limits.MaxCompressionRatio) {
+			return pkg.Wrap(pkg.ErrBadInput, nil, op,
+				fmt.Sprintf("archive entry %q compression ratio exceeds limit of %d", name, limits.MaxCompressionRatio))
+		}
+	}
+
+	return nil
+}
+
+// entryName is the name an entry is unpacked under, zips made on Windows may separate it with backslashes
+func entryName(name string) string {
+	return path.Clean(strings.ReplaceAll(name, `\`, "/"))
+}
+
+// Files returns the validated archive entries.
+func (a *ArchiveReader) Files() []*zip.File {
+	return a.reader.File
+}
+
+var errArchiveTooLarge = errors.New("archive expands beyond its declared size")
+
+// Open opens an archive entry. Reads fail once the archive as a whole
+// produced more than MaxTotalSize bytes, no matter what the headers claim.
+func (a *ArchiveReader) Open(file *zip.File) (io.ReadCloser, error) {
+	const op = "ArchiveReader.Open"
+
+	rc, err := file.Open()
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrBadInput, err, op, fmt.Sprintf("failed to open archive entry %q", file.Name))
+	}
+
+	return &limitedEntry{rc: rc, name: file.Name, archive: a}, nil
+}
+
+type limitedEntry struct {
+	rc      io.ReadCloser
+	name    string
+	archive *ArchiveReader
+}
+
+func (e *limitedEntry) Read(p []byte) (int, error) {
+	const op = "ArchiveReader.Read"
+
+	n, err := e.rc.Read(p)
+	e.archive.read += int64(n)
+
+	if e.archive.read > e.archive.limits.MaxTotalSize {
+		return n, pkg.Wrap(pkg.ErrBadInput, errArchiveTooLarge, op,
+			fmt.Sprintf("archive uncompressed size exceeds limit of %d bytes", e.archive.limits.MaxTotalSize))
+	}
+
+	if err != nil && !errors.Is(err, io.EOF) {
+		// archive/zip reports entries longer than their header as zip.ErrFormat
+		return n, pkg.Wrap(pkg.ErrBadInput, err, op, fmt.Sprintf("failed to read archive entry %q", e.name))
+	}
+
+	return n, err
+}
+
+func (e *limitedEntry) Close() error {
+	return e.rc.Close()
+}
+
+// Extract unpacks entries accepted by filter into destPath.
+func (a *ArchiveReader) Extract(destPath string, filter func(name string) bool) error {
+	const op = "ArchiveReader.Extract"
+
+	root, err := filepath.Abs(destPath)
+	if err != nil {
+		return pkg.Wrap(pkg.ErrInternal, err, op, "failed to resolve destination")
+	}
+
+	for _, file := range a.reader.File {
+		name := entryName(file.Name)
+		if filter != nil && !filter(name) {
+			continue
+		}
+
+		destFilePath := filepath.Join(root, filepath.FromSlash(name))
+		if destFilePath != root && !strings.HasPrefix(destFilePath, root+string(os.PathSeparator)) {
+			return pkg.Wrap(pkg.ErrBadInput, nil, op,
+				fmt.Sprintf("archive entry %q escapes destination directory", file.Name))
+		}
+
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(destFilePath, 0755); err != nil {
+				return pkg.Wrap(pkg.ErrInternal, err, op, "failed to create directory")
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(destFilePath), 0755); err != nil {
+			return pkg.Wrap(pkg.ErrInternal, err, op, "failed to create directory")
+		}
+
+		if err := a.extractFile(file, destFilePath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *ArchiveReader) extractFile(file *zip.File, destFilePath string) error {
+	const op = "ArchiveReader.extractFile"
+
+	rc, err := a.Open(file)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	outFile, err := os.OpenFile(destFilePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return pkg.Wrap(pkg.ErrInternal, err, op, "failed to create file")
+	}
+	defer outFile.Close()
+
+	_, err = io.Copy(outFile, rc)
+	if err != nil {
+		var cErr *pkg.CustomError
+		if errors.As(err, &cErr) {
+			return err
+		}
+		return pkg.Wrap(pkg.ErrInternal, err, op, "failed to write file")
+	}
+
+	return nil
+}
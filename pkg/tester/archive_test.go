@@ -0,0 +1,327 @@
+package tester_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"github.com/Vyacheslav1557/tester/pkg/tester"
+	"github.com/stretchr/testify/require"
+	"hash/crc32"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type entry struct {
+	name string
+	body []byte
+	mode os.FileMode
+}
+
+func buildZip(t *testing.T, entries ...entry) *bytes.Reader {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	w := zip.NewWriter(buf)
+
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		if e.mode != 0 {
+			header.SetMode(e.mode)
+		}
+
+		f, err := w.CreateHeader(header)
+		require.NoError(t, err)
+
+		_, err = f.Write(e.body)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Close())
+
+	return bytes.NewReader(buf.Bytes())
+}
+
+// buildLyingZip stores an entry whose header claims declaredSize bytes
+// while the deflate stream expands to len(body) bytes.
+func buildLyingZip(t *testing.T, name string, body []byte, declaredSize uint64) *bytes.Reader {
+	t.Helper()
+
+	compressed := &bytes.Buffer{}
+	fw, err := flate.NewWriter(compressed, flate.BestCompression)
+	require.NoError(t, err)
+	_, err = fw.Write(body)
+	require.NoError(t, err)
+	require.NoError(t, fw.Close())
+
+	buf := &bytes.Buffer{}
+	w := zip.NewWriter(buf)
+
+	f, err := w.CreateRaw(&zip.FileHeader{
+		Name:               name,
+		Method:             zip.Deflate,
+		CRC32:              crc32.ChecksumIEEE(body),
+		CompressedSize64:   uint64(compressed.Len()),
+		UncompressedSize64: declaredSize,
+	})
+	require.NoError(t, err)
+
+	_, err = f.Write(compressed.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	return bytes.NewReader(buf.Bytes())
+}
+
+func readAll(t *testing.T, archive *tester.ArchiveReader) error {
+	t.Helper()
+
+	for _, file := range archive.Files() {
+		rc, err := archive.Open(file)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(io.Discard, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func TestNewArchiveReader(t *testing.T) {
+	t.Parallel()
+
+	limits := tester.ArchiveLimits{
+		MaxFiles:            4,
+		MaxTotalSize:        8 * 1024 * 1024,
+		MaxCompressionRatio: 100,
+	}
+
+	t.Run("success", func(t *testing.T) {
+		r := buildZip(t,
+			entry{name: "tests/"},
+			entry{name: "tests/01", body: []byte("1 2\n")},
+			entry{name: "tests/01.a", body: []byte("3\n")},
+		)
+
+		archive, err := tester.NewArchiveReader(r, r.Size(), limits)
+		require.NoError(t, err)
+		require.Len(t, archive.Files(), 3)
+		require.NoError(t, readAll(t, archive))
+	})
+
+	malicious := []struct {
+		name string
+		r    func(t *testing.T) *bytes.Reader
+		msg  string
+	}{
+		{
+			name: "path traversal",
+			r: func(t *testing.T) *bytes.Reader {
+				return buildZip(t, entry{name: "tests/../../etc/cron.d/evil", body: []byte("x")})
+			},
+			msg: `archive entry "tests/../../etc/cron.d/evil" escapes destination directory`,
+		},
+		{
+			name: "backslash path traversal",
+			r: func(t *testing.T) *bytes.Reader {
+				return buildZip(t, entry{name: `tests\..\..\evil`, body: []byte("x")})
+			},
+			msg: `archive entry "tests\\..\\..\\evil" escapes destination directory`,
+		},
+		{
+			name: "absolute path",
+			r: func(t *testing.T) *bytes.Reader {
+				return buildZip(t, entry{name: "/etc/passwd", body: []byte("x")})
+			},
+			msg: `archive entry "/etc/passwd" has absolute path`,
+		},
+		{
+			name: "symlink",
+			r: func(t *testing.T) *bytes.Reader {
+				return buildZip(t, entry{name: "tests/01", body: []byte("/etc/shadow"), mode: os.ModeSymlink | 0777})
+			},
+			msg: `archive entry "tests/01" is a symlink`,
+		},
+		{
+			name: "named pipe",
+			r: func(t *testing.T) *bytes.Reader {
+				return buildZip(t, entry{name: "tests/01", mode: os.ModeNamedPipe | 0644})
+			},
+			msg: `archive entry "tests/01" is not a regular file`,
+		},
+		{
+			name: "too many files",
+			r: func(t *testing.T) *bytes.Reader {
+				return buildZip(t,
+					entry{name: "tests/01"}, entry{name: "tests/02"}, entry{name: "tests/03"},
+					entry{name: "tests/04"}, entry{name: "tests/05"},
+				)
+			},
+			msg: "archive has 5 entries, limit is 4",
+		},
+		{
+			name: "total size",
+			r: func(t *testing.T) *bytes.Reader {
+				body := make([]byte, 4*1024*1024) // incompressible 4 MB
+				rand.New(rand.NewSource(1)).Read(body)
+				return buildZip(t,
+					entry{name: "tests/01", body: body},
+					entry{name: "tests/02", body: body},
+					entry{name: "tests/03", body: body},
+				)
+			},
+			msg: "archive uncompressed size exceeds limit of 8388608 bytes",
+		},
+		{
+			name: "compression ratio",
+			r: func(t *testing.T) *bytes.Reader {
+				return buildZip(t, entry{name: "tests/01", body: make([]byte, 4*1024*1024)})
+			},
+			msg: `archive entry "tests/01" compression ratio exceeds limit of 100`,
+		},
+	}
+
+	for _, tc := range malicious {
+		t.Run(tc.name, func(t *testing.T) {
+			r := tc.r(t)
+
+			_, err := tester.NewArchiveReader(r, r.Size(), limits)
+			require.ErrorIs(t, err, pkg.ErrBadInput)
+
+			var cErr *pkg.CustomError
+			require.ErrorAs(t, err, &cErr)
+			require.Equal(t, tc.msg, cErr.Message)
+		})
+	}
+
+	t.Run("lying header", func(t *testing.T) {
+		// claims 1 KB, expands to 64 MB of zeros
+		r := buildLyingZip(t, "tests/01", make([]byte, 64*1024*1024), 1024)
+
+		archive, err := tester.NewArchiveReader(r, r.Size(), limits)
+		require.NoError(t, err)
+
+		err = readAll(t, archive)
+		require.ErrorIs(t, err, pkg.ErrBadInput)
+	})
+
+	t.Run("not a zip", func(t *testing.T) {
+		r := bytes.NewReader([]byte("definitely not a zip"))
+
+		_, err := tester.NewArchiveReader(r, r.Size(), limits)
+		require.ErrorIs(t, err, pkg.ErrBadInput)
+	})
+}
+
+func TestArchiveReader_Extract(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		r := buildZip(t,
+			entry{name: "statements/russian/problem-properties.json", body: []byte("{}")},
+			entry{name: "tests/01", body: []byte("1 2\n")},
+			entry{name: "tests/01.a", body: []byte("3\n")},
+		)
+
+		archive, err := tester.NewArchiveReader(r, r.Size(), tester.DefaultArchiveLimits)
+		require.NoError(t, err)
+
+		dest := t.TempDir()
+		err = archive.Extract(dest, func(name string) bool {
+			return filepath.Dir(name) == "tests"
+		})
+		require.NoError(t, err)
+
+		b, err := os.ReadFile(filepath.Join(dest, "tests", "01.a"))
+		require.NoError(t, err)
+		require.Equal(t, "3\n", string(b))
+
+		_, err = os.Stat(filepath.Join(dest, "statements"))
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("backslashes", func(t *testing.T) {
+		r := buildZip(t,
+			entry{name: `.\main.cpp`, body: []byte("int main() {}")},
+			entry{name: `lib\lib.h`, body: []byte("#pragma once")},
+		)
+
+		archive, err := tester.NewArchiveReader(r, r.Size(), tester.DefaultArchiveLimits)
+		require.NoError(t, err)
+
+		dest := t.TempDir()
+		var names []string
+		err = archive.Extract(dest, func(name string) bool {
+			names = append(names, name)
+			return true
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"main.cpp", "lib/lib.h"}, names)
+
+		for _, name := range []string{"main.cpp", filepath.Join("lib", "lib.h")} {
+			_, err = os.Stat(filepath.Join(dest, name))
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("lying header", func(t *testing.T) {
+		limits := tester.DefaultArchiveLimits
+		limits.MaxTotalSize = 1024 * 1024
+
+		r := buildLyingZip(t, "tests/01", make([]byte, 16*1024*1024), 1024)
+
+		archive, err := tester.NewArchiveReader(r, r.Size(), limits)
+		require.NoError(t, err)
+
+		err = archive.Extract(t.TempDir(), nil)
+		require.ErrorIs(t, err, pkg.ErrBadInput)
+	})
+}
+
+func TestCheckArchive(t *testing.T) {
+	t.Parallel()
+
+	data := func(entries ...entry) []byte {
+		b, err := io.ReadAll(buildZip(t, entries...))
+		require.NoError(t, err)
+		return b
+	}
+
+	t.Run("success", func(t *testing.T) {
+		err := tester.CheckArchive(models.Cpp, data(
+			entry{name: "main.cpp", body: []byte("#include \"lib.h\"\nint main() {}")},
+			entry{name: "lib/lib.h", body: []byte("#pragma once")},
+		))
+		require.NoError(t, err)
+
+		err = tester.CheckArchive(models.Python, data(entry{name: "__main__.py", body: []byte("print(1)")}))
+		require.NoError(t, err)
+	})
+
+	t.Run("no entrypoint", func(t *testing.T) {
+		err := tester.CheckArchive(models.Cpp, data(entry{name: "src/main.cpp", body: []byte("int main() {}")}))
+		require.ErrorIs(t, err, pkg.ErrBadInput)
+	})
+
+	t.Run("not a zip", func(t *testing.T) {
+		err := tester.CheckArchive(models.Golang, []byte("package main"))
+		require.ErrorIs(t, err, pkg.ErrBadInput)
+	})
+
+	t.Run("escapes", func(t *testing.T) {
+		err := tester.CheckArchive(models.Golang, data(
+			entry{name: "main.go", body: []byte("package main")},
+			entry{name: "../evil.go", body: []byte("package main")},
+		))
+		require.ErrorIs(t, err, pkg.ErrBadInput)
+	})
+}
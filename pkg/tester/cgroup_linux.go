@@ -0,0 +1,183 @@
+package tester
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	cgroupControllers = "+cpu +memory +pids"
+	swapMax           = "program/memory.swap.max"
+)
+
+// cgroup is the cgroup v2 subtree of a single run. The sandbox init process
+// lives in init, the program in program, so the limits and the stats only
+// cover the program itself.
+type cgroup struct {
+	path    string
+	init    *os.File
+	program *os.File
+}
+
+type cgroupLimits struct {
+	memory int64 // bytes
+	pids   int64
+}
+
+func newCgroup(path string, limits cgroupLimits) (c *cgroup, err error) {
+	c = &cgroup{path: path}
+	defer func() {
+		if err != nil {
+			c.remove()
+		}
+	}()
+
+	for _, dir := range []string{path, filepath.Join(path, "init"), filepath.Join(path, "program")} {
+		if err = os.Mkdir(dir, 0755); err != nil {
+			return nil, err
+		}
+
+		if dir == path {
+			if err = c.write("cgroup.subtree_control", cgroupControllers); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for file, value := range map[string]string{
+		"memory.max":      strconv.FormatInt(limits.memory, 10),
+		"memory.swap.max": "0",
+		"pids.max":        strconv.FormatInt(limits.pids, 10),
+		"cpu.max":         "100000 100000", // a single CPU
+	} {
+		file = filepath.Join("program", file)
+
+		// swap accounting may be disabled, there is no swap to limit then
+		if _, statErr := os.Stat(filepath.Join(path, file)); errors.Is(statErr, os.ErrNotExist) && file == swapMax {
+			continue
+		}
+
+		if err = c.write(file, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.init, err = os.Open(filepath.Join(path, "init")); err != nil {
+		return nil, err
+	}
+	if c.program, err = os.Open(filepath.Join(path, "program")); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *cgroup) write(file, value string) error {
+	return os.WriteFile(filepath.Join(c.path, file), []byte(value), 0)
+}
+
+// kill kills every process of the run, the init process included
+func (c *cgroup) kill() error {
+	return c.write("cgroup.kill", "1")
+}
+
+func (c *cgroup) stats() (cgroupUsage, error) {
+	// memory.peak needs linux 5.19
+	peak, err := os.Open(filepath.Join(c.path, "program", "memory.peak"))
+	if err != nil {
+		return cgroupUsage{}, err
+	}
+	defer peak.Close()
+
+	return readCgroupUsage(filepath.Join(c.path, "program"), peak)
+}
+
+// remove deletes the subtree, the kernel may take a moment
+// to release it after the last process has exited
+func (c *cgroup) remove() error {
+	for _, f := range []*os.File{c.init, c.program} {
+		if f != nil {
+			f.Close()
+		}
+	}
+
+	var err error
+	for _, dir := range []string{filepath.Join(c.path, "init"), filepath.Join(c.path, "program"), c.path} {
+		for i := 0; i < 50; i++ {
+			err = os.Remove(dir)
+			if err == nil || errors.Is(err, os.ErrNotExist) {
+				err = nil
+				break
+			}
+			if !errors.Is(err, syscall.EBUSY) {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readCgroupUsage reads the counters of the cgroup dir,
+// peak is its memory.peak opened by the caller
+func readCgroupUsage(dir string, peak io.ReaderAt) (cgroupUsage, error) {
+	var usage cgroupUsage
+
+	cpu, err := os.ReadFile(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return usage, err
+	}
+	usage.cpu = time.Duration(parseKeyed(cpu)["usage_usec"]) * time.Microsecond
+
+	events, err := os.ReadFile(filepath.Join(dir, "memory.events"))
+	if err != nil {
+		return usage, err
+	}
+	usage.oomKills = parseKeyed(events)["oom_kill"]
+
+	buf := make([]byte, 32)
+	n, err := peak.ReadAt(buf, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return usage, err
+	}
+	usage.peak, err = strconv.ParseInt(strings.TrimSpace(string(buf[:n])), 10, 64)
+	if err != nil {
+		return usage, fmt.Errorf("failed to parse memory.peak: %w", err)
+	}
+
+	return usage, nil
+}
+
+// parseKeyed parses flat keyed files like cpu.stat and memory.events
+func parseKeyed(data []byte) map[string]int64 {
+	res := make(map[string]int64)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), " ")
+		if !ok {
+			continue
+		}
+
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		res[key] = n
+	}
+
+	return res
+}
@@ -2,6 +2,7 @@ package tester
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"github.com/Vyacheslav1557/tester/pkg"
 	"math"
@@ -10,6 +11,21 @@ import (
 	"strings"
 )
 
+// Checker compares the output of a test with the answer. It returns the share
+// of the points of the test the output gets, from 0 to 1, along with a verdict
+// unless the share is 1, e.g. a partially correct answer is a wrong one.
+type Checker func(ctx context.Context, inputPath, answerPath, outputPath string) (float64, error)
+
+// checkTokens is the default checker, the outputs must match token by token,
+// floating point numbers up to 1e-6
+func checkTokens(_ context.Context, _, answerPath, outputPath string) (float64, error) {
+	err := compareFiles(answerPath, outputPath, 1e-6)
+	if err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
 func isFloat(s string) bool {
 	_, err := strconv.ParseFloat(s, 64)
 	return err == nil
@@ -1,116 +1,393 @@
 package tester
 
 import (
+	"bytes"
+	_ "embed"
+	"fmt"
 	"github.com/Vyacheslav1557/tester/internal/models"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
-type Config interface {
-	Image() string
-	CompileTL() time.Duration
-	CompileML() int64
-	CompileCMD() []string
-	ExecuteCMD() []string
+const (
+	defaultCompileTL = time.Second * 20
+	defaultCompileML = 256 // MB
+)
+
+// Language describes how to build and run solutions written in it
+type Language struct {
+	Id         models.LanguageName `yaml:"id"`
+	Name       string              `yaml:"name"` // display name
+	Image      string              `yaml:"image"`
+	SourceFile string              `yaml:"source_file"`
+
+	// PublicClass is a regular expression finding the public class in the source, which has to be
+	// declared in a file of its name: its first group replaces {public_class} in the source file name
+	// and compile commands. The class with main is up to the compile commands, it need not be public.
+	PublicClass string `yaml:"public_class"`
+	// Package is a regular expression matching package declarations, they are blanked out
+	// of single sources, which are built in the default package
+	Package string `yaml:"package"`
+
+	Compile        []string      `yaml:"compile"`
+	CompileTimeout time.Duration `yaml:"compile_timeout"`
+	CompileMemory  int64         `yaml:"compile_memory"` // MB
+	// {memory_limit} in the execute commands is replaced with the problem's memory limit in MB
+	Execute []string `yaml:"execute"`
+
+	// limits of a problem are multiplied by these for solutions in this language
+	TimeMultiplier   float64 `yaml:"time_multiplier"`
+	MemoryMultiplier float64 `yaml:"memory_multiplier"`
+	// and these are added on top, e.g. for the JVM startup and heap overhead
+	TimeAllowance   int64 `yaml:"time_allowance"`   // ms
+	MemoryAllowance int64 `yaml:"memory_allowance"` // MB
+
+	PolygonTypes []string `yaml:"polygon_types"`
+
+	// Archive builds the solutions submitted as a zip of files, nil if the language takes single files only
+	Archive *ArchiveBuild `yaml:"archive"`
+
+	// Grader builds the solutions of the problems with a jury-provided grader, nil if the language has no such build
+	Grader *GraderBuild `yaml:"grader"`
+
+	// WarmPool is used by the Docker executor only
+	WarmPool WarmPool `yaml:"warm_pool"`
+
+	Enabled bool `yaml:"enabled"`
+
+	publicClass *regexp.Regexp
+	packageDecl *regexp.Regexp
 }
 
-func GetConfig(lang models.LanguageName) Config {
-	switch lang {
-	case models.Cpp:
-		return CppConfig{}
-	case models.Golang:
-		return GolangConfig{}
-	case models.Python:
-		return PythonConfig{}
-	}
+// ArchiveBuild is how a zip of files is built. The files are unpacked
+// to /code/src and the entrypoint, e.g. main.cpp, has to be among them.
+type ArchiveBuild struct {
+	Entrypoint string   `yaml:"entrypoint"`
+	Compile    []string `yaml:"compile"`
+}
 
-	return nil
+// GraderBuild is how a solution is linked with the grader of a problem.
+// The grader is written to /code/<file> next to the source.
+type GraderBuild struct {
+	File    string   `yaml:"file"`
+	Compile []string `yaml:"compile"`
+}
+
+// CompileML returns the memory limit of compilation in bytes
+func (l *Language) CompileML() int64 {
+	return l.CompileMemory * 1024 * 1024
+}
+
+// TL scales the problem's time limit for this language
+func (l *Language) TL(tl int64) int64 {
+	return int64(float64(tl)*l.TimeMultiplier) + l.TimeAllowance
+}
+
+// ML scales the problem's memory limit for this language
+func (l *Language) ML(ml int64) int64 {
+	return int64(float64(ml)*l.MemoryMultiplier) + l.MemoryAllowance
+}
+
+// Limits are the limits of a problem, before they are adjusted for a language
+type Limits struct {
+	TL int64 // ms
+	ML int64 // MB
+	OL int64 // bytes of stdout
 }
 
 const (
-	defaultCompileTL = time.Second * 20
-	defaultCompileML = 256 * 1024 * 1024
+	defaultTL = 10000            // ms
+	defaultML = 256              // MB
+	defaultOL = 64 * 1024 * 1024 // 64 MB
 )
 
-type CppConfig struct{}
+func (l Limits) withDefaults() Limits {
+	if l.TL <= 0 {
+		l.TL = defaultTL
+	}
+	if l.ML <= 0 {
+		l.ML = defaultML
+	}
+	if l.OL <= 0 {
+		l.OL = defaultOL
+	}
+	return l
+}
 
-func (c CppConfig) Image() string {
-	return "custom-golang:1.20custom-golang:1.20"
+// CPULimit returns the CPU time limit of the sandbox in whole seconds, for RLIMIT_CPU
+func (l *Language) CPULimit(limits Limits) int64 {
+	tl := l.TL(limits.withDefaults().TL)
+	return (tl + 999) / 1000
 }
 
-func (c CppConfig) CompileTL() time.Duration {
-	return defaultCompileTL
+// ExecuteCMD returns the execute commands with the limits filled in
+func (l *Language) ExecuteCMD(limits Limits) []string {
+	r := strings.NewReplacer("{memory_limit}", strconv.FormatInt(limits.withDefaults().ML, 10))
+
+	cmd := make([]string, len(l.Execute))
+	for i, arg := range l.Execute {
+		cmd[i] = r.Replace(arg)
+	}
+	return cmd
 }
 
-func (c CppConfig) CompileML() int64 {
-	return defaultCompileML
+// defaultPublicClass names the source file if there is no public class in it
+const defaultPublicClass = "Main"
+
+// forSource resolves {public_class} for the given source
+func (l *Language) forSource(source []byte) *Language {
+	if l.publicClass == nil {
+		return l
+	}
+
+	publicClass := defaultPublicClass
+	if m := l.publicClass.FindSubmatch(source); m != nil {
+		publicClass = string(m[1])
+	}
+
+	r := strings.NewReplacer("{public_class}", publicClass)
+
+	resolved := *l
+	resolved.SourceFile = r.Replace(l.SourceFile)
+	resolved.Compile = replaceAll(r, l.Compile)
+	if l.Grader != nil {
+		resolved.Grader = &GraderBuild{
+			File:    l.Grader.File,
+			Compile: replaceAll(r, l.Grader.Compile),
+		}
+	}
+
+	return &resolved
 }
 
-func (c CppConfig) CompileCMD() []string {
-	return []string{
-		"bash", "-c",
-		"ln -s /code/source /code/source.cpp && g++ -o /code/solution /code/source.cpp",
+// stripPackage blanks the package declarations out of the source, keeping its lines in place
+func (l *Language) stripPackage(source []byte) []byte {
+	if l.packageDecl == nil {
+		return source
 	}
+
+	return l.packageDecl.ReplaceAllFunc(source, func(decl []byte) []byte {
+		return bytes.Map(func(r rune) rune {
+			if r == '\n' {
+				return r
+			}
+			return ' '
+		}, decl)
+	})
 }
 
-func (c CppConfig) ExecuteCMD() []string {
-	return []string{
-		"bash", "-c",
-		"/usr/bin/time -v -o /dev/stderr bash -c 'ulimit -t 30; /code/solution'",
+func replaceAll(r *strings.Replacer, args []string) []string {
+	res := make([]string, len(args))
+	for i, arg := range args {
+		res[i] = r.Replace(arg)
 	}
+	return res
 }
 
-type GolangConfig struct{}
+// forArchive uses the compile commands of archives
+func (l *Language) forArchive() *Language {
+	resolved := *l
+	resolved.Compile = l.Archive.Compile
+	return &resolved
+}
 
-func (c GolangConfig) Image() string {
-	return "custom-golang:1.20"
+// forGrader uses the compile commands linking the source with a grader
+func (l *Language) forGrader() *Language {
+	resolved := *l
+	resolved.Compile = l.Grader.Compile
+	return &resolved
 }
 
-func (c GolangConfig) CompileTL() time.Duration {
-	return 60 * time.Second
+func (l *Language) validate() error {
+	switch {
+	case l.Id <= 0:
+		return fmt.Errorf("language %q: id must be positive", l.Name)
+	case l.Name == "":
+		return fmt.Errorf("language %d: name is required", l.Id)
+	case l.Image == "":
+		return fmt.Errorf("language %d: image is required", l.Id)
+	case l.SourceFile == "" || l.SourceFile != filepath.Base(l.SourceFile) || l.SourceFile == "solution" ||
+		(l.PublicClass == "" && strings.Contains(l.SourceFile, "{public_class}")):
+		return fmt.Errorf("language %d: invalid source file %q", l.Id, l.SourceFile)
+	case len(l.Execute) == 0:
+		return fmt.Errorf("language %d: execute command is required", l.Id)
+	case l.TimeMultiplier < 0 || l.MemoryMultiplier < 0:
+		return fmt.Errorf("language %d: multipliers must be positive", l.Id)
+	case l.TimeAllowance < 0 || l.MemoryAllowance < 0:
+		return fmt.Errorf("language %d: allowances must not be negative", l.Id)
+	case l.WarmPool.Size < 0 || l.WarmPool.MaxUses < 0:
+		return fmt.Errorf("language %d: invalid warm pool", l.Id)
+	case l.Archive != nil && (len(l.Archive.Compile) == 0 || !validArchivePath(l.Archive.Entrypoint)):
+		return fmt.Errorf("language %d: archives need an entrypoint and compile commands", l.Id)
+	case l.Grader != nil && (len(l.Grader.Compile) == 0 || l.Grader.File == "" ||
+		l.Grader.File != filepath.Base(l.Grader.File) || l.Grader.File == l.SourceFile || l.Grader.File == "solution"):
+		return fmt.Errorf("language %d: graders need a file and compile commands", l.Id)
+	}
+
+	if l.PublicClass != "" {
+		re, err := regexp.Compile(l.PublicClass)
+		if err != nil || re.NumSubexp() < 1 {
+			return fmt.Errorf("language %d: public class must be a regular expression with a group", l.Id)
+		}
+		l.publicClass = re
+	}
+
+	if l.Package != "" {
+		re, err := regexp.Compile(l.Package)
+		if err != nil {
+			return fmt.Errorf("language %d: package must be a regular expression", l.Id)
+		}
+		l.packageDecl = re
+	}
+
+	if l.CompileTimeout == 0 {
+		l.CompileTimeout = defaultCompileTL
+	}
+	if l.CompileMemory == 0 {
+		l.CompileMemory = defaultCompileML
+	}
+	if l.TimeMultiplier == 0 {
+		l.TimeMultiplier = 1
+	}
+	if l.MemoryMultiplier == 0 {
+		l.MemoryMultiplier = 1
+	}
+	if l.WarmPool.MaxUses == 0 {
+		l.WarmPool.MaxUses = defaultWarmMaxUses
+	}
+
+	return nil
 }
 
-func (c GolangConfig) CompileML() int64 {
-	return defaultCompileML
+// Registry holds the languages loaded at startup
+type Registry struct {
+	languages []*Language
+	byId      map[models.LanguageName]*Language
 }
 
-func (c GolangConfig) CompileCMD() []string {
-	return []string{
-		"bash", "-c",
-		"ln -s /code/source /code/source.go && GO111MODULE=off go build -o /code/solution /code/source.go",
+func ParseRegistry(data []byte) (*Registry, error) {
+	var languages []*Language
+	if err := yaml.Unmarshal(data, &languages); err != nil {
+		return nil, fmt.Errorf("failed to parse languages: %w", err)
 	}
-}
 
-func (c GolangConfig) ExecuteCMD() []string {
-	return []string{
-		"bash", "-c",
-		"/usr/bin/time -v -o /dev/stderr bash -c 'ulimit -t 30; /code/solution'",
+	r := &Registry{
+		byId: make(map[models.LanguageName]*Language, len(languages)),
+	}
+
+	for _, lang := range languages {
+		if err := lang.validate(); err != nil {
+			return nil, err
+		}
+
+		if _, ok := r.byId[lang.Id]; ok {
+			return nil, fmt.Errorf("language %d: duplicate id", lang.Id)
+		}
+
+		r.byId[lang.Id] = lang
+		r.languages = append(r.languages, lang)
 	}
+
+	sort.Slice(r.languages, func(i, j int) bool {
+		return r.languages[i].Id < r.languages[j].Id
+	})
+
+	return r, nil
 }
 
-type PythonConfig struct{}
+func LoadRegistry(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read languages: %w", err)
+	}
 
-func (c PythonConfig) Image() string {
-	return "custom-python:3.9"
+	return ParseRegistry(data)
 }
 
-func (c PythonConfig) CompileTL() time.Duration {
-	return defaultCompileTL
+// Get returns an enabled language, nil if there is none with the id
+func (r *Registry) Get(id models.LanguageName) *Language {
+	lang, ok := r.byId[id]
+	if !ok || !lang.Enabled {
+		return nil
+	}
+	return lang
+}
+
+// List returns the enabled languages ordered by id
+func (r *Registry) List() []*Language {
+	res := make([]*Language, 0, len(r.languages))
+	for _, lang := range r.languages {
+		if lang.Enabled {
+			res = append(res, lang)
+		}
+	}
+	return res
 }
 
-func (c PythonConfig) CompileML() int64 {
-	return defaultCompileML
+// ByPolygonType finds an enabled language by a polygon source type, e.g. "cpp.g++17"
+func (r *Registry) ByPolygonType(sourceType string) *Language {
+	for _, lang := range r.List() {
+		for _, prefix := range lang.PolygonTypes {
+			if strings.HasPrefix(sourceType, prefix) {
+				return lang
+			}
+		}
+	}
+	return nil
 }
 
-func (c PythonConfig) CompileCMD() []string {
-	return []string{
-		"bash", "-c",
-		`pypy3 -c 'import py_compile; py_compile.compile("/code/source", doraise=True)' && cp /code/source /code/solution`,
+func (r *Registry) ids() []models.LanguageName {
+	ids := make([]models.LanguageName, 0, len(r.languages))
+	for _, lang := range r.List() {
+		ids = append(ids, lang.Id)
 	}
+	return ids
+}
+
+//go:embed languages.yaml
+var defaultLanguages []byte
+
+var (
+	registryMu sync.RWMutex
+	registry   = mustParseRegistry(defaultLanguages)
+)
+
+// the models accept the embedded languages until SetRegistry is called
+func init() {
+	models.SetLanguages(registry.ids())
 }
 
-func (c PythonConfig) ExecuteCMD() []string {
-	return []string{
-		"bash", "-c",
-		"/usr/bin/time -v -o /dev/stderr bash -c 'ulimit -t 30; pypy3 /code/solution'",
+func mustParseRegistry(data []byte) *Registry {
+	r, err := ParseRegistry(data)
+	if err != nil {
+		panic(err)
 	}
+	return r
+}
+
+// SetRegistry replaces the languages in use, the embedded languages.yaml is used until then
+func SetRegistry(r *Registry) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registry = r
+	models.SetLanguages(r.ids())
+}
+
+func Languages() *Registry {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	return registry
+}
+
+func GetConfig(lang models.LanguageName) *Language {
+	return Languages().Get(lang)
 }
@@ -0,0 +1,131 @@
+package tester_test
+
+import (
+	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/Vyacheslav1557/tester/pkg/tester"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestParseRegistry(t *testing.T) {
+	t.Parallel()
+
+	t.Run("defaults", func(t *testing.T) {
+		registry := tester.Languages()
+
+		for _, id := range []models.LanguageName{models.Golang, models.Cpp, models.Python, models.Java, models.Kotlin} {
+			lang := registry.Get(id)
+			require.NotNil(t, lang)
+			require.NotEmpty(t, lang.Image)
+			require.NotEmpty(t, lang.Execute)
+		}
+
+		require.Equal(t, "custom-golang:1.20", registry.Get(models.Cpp).Image)
+		require.Equal(t, models.Cpp, registry.ByPolygonType("cpp.g++17").Id)
+		require.Equal(t, models.Python, registry.ByPolygonType("python.3").Id)
+		require.Equal(t, models.Golang, registry.ByPolygonType("go").Id)
+		require.Equal(t, models.Java, registry.ByPolygonType("java21").Id)
+		require.Equal(t, models.Kotlin, registry.ByPolygonType("kotlin1.9").Id)
+		require.Nil(t, registry.ByPolygonType("pascal.fpc"))
+		require.Equal(t, "__main__.py", registry.Get(models.Python).Archive.Entrypoint)
+		require.Equal(t, "Grader.java", registry.Get(models.Java).Grader.File)
+
+		java := registry.Get(models.Java)
+		require.Equal(t, int64(1300), java.TL(1000))
+		require.Equal(t, int64(448), java.ML(256))
+		require.Equal(t, int64(2), java.CPULimit(tester.Limits{TL: 1000, ML: 256}))
+		require.Contains(t, java.ExecuteCMD(tester.Limits{TL: 1000, ML: 256}), "-Xmx256m")
+		require.Contains(t, java.ExecuteCMD(tester.Limits{TL: 1000, ML: 256}), "-Xss256m")
+		require.Equal(t, int64(10), registry.Get(models.Golang).CPULimit(tester.Limits{}))
+
+		// the models accept exactly the enabled languages of the registry
+		ids := make([]models.LanguageName, 0)
+		for _, lang := range registry.List() {
+			ids = append(ids, lang.Id)
+		}
+		require.Equal(t, ids, models.EnabledLanguages())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		registry, err := tester.ParseRegistry([]byte(`
+- id: 40
+  name: Java 21
+  image: eclipse-temurin:21
+  source_file: Main.java
+  compile: [javac, Main.java]
+  execute: [java, Main]
+  time_multiplier: 2
+  enabled: true
+- id: 50
+  name: Rust
+  image: rust:1
+  source_file: main.rs
+  execute: [./solution]
+`))
+		require.NoError(t, err)
+
+		java := registry.Get(40)
+		require.NotNil(t, java)
+		require.Equal(t, int64(2000), java.TL(1000))
+		require.Equal(t, int64(256), java.ML(256))
+		require.Equal(t, 20*time.Second, java.CompileTimeout)
+		require.Equal(t, int64(256*1024*1024), java.CompileML())
+
+		require.Nil(t, registry.Get(50), "disabled")
+		require.Len(t, registry.List(), 1)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for name, data := range map[string]string{
+			"duplicate id": `
+- {id: 1, name: a, image: a, source_file: a, execute: [a], enabled: true}
+- {id: 1, name: b, image: b, source_file: b, execute: [b], enabled: true}`,
+			"no image":           `[{id: 1, name: a, source_file: a, execute: [a]}]`,
+			"no execute":         `[{id: 1, name: a, image: a, source_file: a}]`,
+			"bad source":         `[{id: 1, name: a, image: a, source_file: ../a, execute: [a]}]`,
+			"not a list":         `id: 1`,
+			"negative ratio":     `[{id: 1, name: a, image: a, source_file: a, execute: [a], time_multiplier: -1}]`,
+			"negative allowance": `[{id: 1, name: a, image: a, source_file: a, execute: [a], memory_allowance: -1}]`,
+			"no public class":    `[{id: 1, name: a, image: a, source_file: "{public_class}.java", execute: [a]}]`,
+			"public class group": `[{id: 1, name: a, image: a, source_file: "{public_class}.java", public_class: "class", execute: [a]}]`,
+			"archive compile":    `[{id: 1, name: a, image: a, source_file: a, execute: [a], archive: {entrypoint: a}}]`,
+			"archive entrypoint": `[{id: 1, name: a, image: a, source_file: a, execute: [a], archive: {entrypoint: ../a, compile: [a]}}]`,
+			"grader compile":     `[{id: 1, name: a, image: a, source_file: a, execute: [a], grader: {file: b}}]`,
+			"grader file":        `[{id: 1, name: a, image: a, source_file: a, execute: [a], grader: {file: a, compile: [a]}}]`,
+		} {
+			t.Run(name, func(t *testing.T) {
+				_, err := tester.ParseRegistry([]byte(data))
+				require.Error(t, err)
+			})
+		}
+	})
+}
+
+func TestLanguage_ForSource(t *testing.T) {
+	t.Parallel()
+
+	java := tester.Languages().Get(models.Java)
+	require.NotNil(t, java)
+
+	for name, tc := range map[string]struct {
+		source     string
+		sourceFile string
+	}{
+		"public class": {
+			source:     "public final class Solution {\n\tpublic static void main(String[] args) {}\n}",
+			sourceFile: "Solution.java",
+		},
+		"no public class": {
+			source:     "class Solution {\n\tpublic static void main(String[] args) {}\n}",
+			sourceFile: "Main.java",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resolved := java.ForSource([]byte(tc.source))
+			require.Equal(t, tc.sourceFile, resolved.SourceFile)
+			require.Contains(t, resolved.Compile[2], "/code/"+tc.sourceFile)
+			require.NotContains(t, resolved.Compile[2], "{public_class}")
+		})
+	}
+}
@@ -1,8 +1,11 @@
 package tester
 
 import (
+	"errors"
 	"fmt"
 	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"strings"
 )
 
 type StateErr struct {
@@ -21,4 +24,33 @@ var (
 	RuntimeErr             = &StateErr{State: models.GotRE, Msg: "runtime error"}
 	PresentationErr        = &StateErr{State: models.GotPE, Msg: "presentation error"}
 	WrongAnswerErr         = &StateErr{State: models.GotWA, Msg: "wrong answer"}
+
+	OutputLimitExceededErr   = &StateErr{State: models.GotOL, Msg: "output limit exceeded error"}
+	IdlenessLimitExceededErr = &StateErr{State: models.GotIL, Msg: "idleness limit exceeded error"}
 )
+
+const compileErrorPrefix = "compile error: "
+
+// compilationError carries the compiler output in the message
+func compilationError(op, log string) error {
+	return pkg.Wrap(CompilationErr, nil, op, compileErrorPrefix+log)
+}
+
+// CompileLog returns the compiler output of a compilation error, empty for other errors
+func CompileLog(err error) string {
+	var cErr *pkg.CustomError
+	if !errors.Is(err, CompilationErr) || !errors.As(err, &cErr) {
+		return ""
+	}
+
+	// the error may be wrapped on its way up, the innermost one has the output
+	for {
+		var inner *pkg.CustomError
+		if !errors.As(cErr.Cause, &inner) {
+			break
+		}
+		cErr = inner
+	}
+
+	return strings.TrimPrefix(cErr.Message, compileErrorPrefix)
+}
@@ -1,10 +1,10 @@
 package tester
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"github.com/Vyacheslav1557/tester/internal/models"
 	"github.com/Vyacheslav1557/tester/pkg"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
@@ -13,30 +13,46 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
 type Executor interface {
-	Compile(ctx context.Context, cfg Config, path string) error
-	Execute(ctx context.Context, cfg Config, path string, input io.Reader) error
+	Compile(ctx context.Context, lang *Language, path string) error
+	// Execute runs the build in path, stdout goes to output.txt and stderr to stderr.txt.
+	// Metrics are returned along with verdicts of the sandbox as well.
+	Execute(ctx context.Context, lang *Language, limits Limits, path string, input io.Reader) (*Metrics, error)
 }
 
+const wallTimeFactor = 3 // wall time limit as a multiple of the time limit
+
 type DockerExecutor struct {
 	dockerClient *client.Client
+
+	// warm containers of the languages with a pool, see warm.go
+	warmDir string
+	mu      sync.Mutex
+	pools   map[models.LanguageName]chan *execContainer
 }
 
-func NewDockerExecutor(dockerClient *client.Client) *DockerExecutor {
-	return &DockerExecutor{dockerClient: dockerClient}
+// NewDockerExecutor creates the executor, warmDir holds the directories
+// mounted into warm containers
+func NewDockerExecutor(dockerClient *client.Client, warmDir string) *DockerExecutor {
+	return &DockerExecutor{
+		dockerClient: dockerClient,
+		warmDir:      warmDir,
+		pools:        make(map[models.LanguageName]chan *execContainer),
+	}
 }
 
-func (e *DockerExecutor) Compile(ctx context.Context, cfg Config, workDir string) error {
+func (e *DockerExecutor) Compile(ctx context.Context, lang *Language, workDir string) error {
 	const op = "DockerExecutor.Compile"
 
 	var pidsLimit int64 = 100
 	resp, err := e.dockerClient.ContainerCreate(ctx,
 		&container.Config{
-			Image:           cfg.Image(),
-			Cmd:             cfg.CompileCMD(),
+			Image:           lang.Image,
+			Cmd:             lang.Compile,
 			Tty:             false,
 			OpenStdin:       false,
 			NetworkDisabled: true,
@@ -47,7 +63,7 @@ func (e *DockerExecutor) Compile(ctx context.Context, cfg Config, workDir string
 				fmt.Sprintf("%s:/code:rw", workDir),
 			},
 			Resources: container.Resources{
-				Memory:    cfg.CompileML(),
+				Memory:    lang.CompileML(),
 				CPUPeriod: 100000,
 				CPUQuota:  100000,
 				PidsLimit: &pidsLimit,
@@ -69,7 +85,7 @@ func (e *DockerExecutor) Compile(ctx context.Context, cfg Config, workDir string
 		return pkg.Wrap(pkg.ErrInternal, err, op, "failed to start container")
 	}
 
-	timeoutCtx, cancel := context.WithTimeout(ctx, cfg.CompileTL())
+	timeoutCtx, cancel := context.WithTimeout(ctx, lang.CompileTimeout)
 	defer cancel()
 
 	statusCh, errCh := e.dockerClient.ContainerWait(timeoutCtx, resp.ID, container.WaitConditionNotRunning)
@@ -78,7 +94,15 @@ func (e *DockerExecutor) Compile(ctx context.Context, cfg Config, workDir string
 	select {
 	case err := <-errCh:
 		if err != nil {
-			err = errors.Join(e.dockerClient.ContainerKill(ctx, resp.ID, "SIGKILL"), err)
+			killErr := e.dockerClient.ContainerKill(ctx, resp.ID, "SIGKILL")
+
+			// running out of time is the fault of the source, e.g. a template bomb,
+			// the judge would only fail the same way if retried
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil && killErr == nil {
+				return compilationError(op, "compilation timed out")
+			}
+
+			err = errors.Join(killErr, err)
 			return pkg.Wrap(pkg.ErrInternal, err, op, "failed to kill container")
 		}
 	case status := <-statusCh:
@@ -87,152 +111,280 @@ func (e *DockerExecutor) Compile(ctx context.Context, cfg Config, workDir string
 
 	logs, err := e.dockerClient.ContainerLogs(ctx, resp.ID, container.LogsOptions{
 		ShowStderr: true,
-		ShowStdout: false,
+		ShowStdout: true,
 	})
 	if err != nil {
 		return pkg.Wrap(pkg.ErrInternal, err, op, "failed to capture logs")
 	}
 	defer logs.Close()
 
-	var stderrBuf strings.Builder
-	_, err = stdcopy.StdCopy(nil, &stderrBuf, logs)
+	var compileLog strings.Builder
+	output := newLimitedWriter(&compileLog, maxCompileLog)
+	_, err = stdcopy.StdCopy(output, output, logs)
 	if err != nil {
 		return pkg.Wrap(pkg.ErrInternal, err, op, "failed to read logs")
 	}
-	stderrOutput := stderrBuf.String()
 
-	if statusCode != 0 || len(stderrOutput) > 0 {
-		return pkg.Wrap(CompilationErr, nil, op, "compile error: "+stderrOutput)
+	// warnings are fine as long as the compiler succeeds
+	if statusCode != 0 {
+		return compilationError(op, compileLog.String())
 	}
 
 	return nil
 }
 
-func (e *DockerExecutor) Execute(ctx context.Context, cfg Config, workDir string, in io.Reader) error {
+// Execute runs the build in a container of the language's pool, the languages
+// without a pool get a fresh container per run. Resources are measured with
+// the container's cgroup or the stats API of Docker, see stats.go.
+func (e *DockerExecutor) Execute(
+	ctx context.Context,
+	lang *Language,
+	limits Limits,
+	workDir string,
+	in io.Reader,
+) (*Metrics, error) {
 	const op = "DockerExecutor.Execute"
 
-	var pidsLimit int64 = 100
-	resp, err := e.dockerClient.ContainerCreate(ctx,
-		&container.Config{
-			Image:           cfg.Image(),
-			Cmd:             cfg.ExecuteCMD(),
-			Tty:             false,
-			OpenStdin:       true,
-			StdinOnce:       true,
-			NetworkDisabled: true,
-			User:            "1000:1000",
-		},
-		&container.HostConfig{
-			Binds: []string{
-				fmt.Sprintf("%s:/code:ro", workDir),
-			},
-			CapDrop: []string{
-				"ALL",
-			},
-			CapAdd: []string{
-				"SYS_CHROOT",
-			},
-			Resources: container.Resources{
-				Memory:    256 * 1024 * 1024,
-				CPUPeriod: 100000,
-				CPUQuota:  100000,
-				PidsLimit: &pidsLimit,
-			},
-			SecurityOpt: []string{
-				"apparmor:docker-default",
-			},
-			ReadonlyRootfs: true,
-		},
-		nil,
-		nil,
-		"",
-	)
+	limits = limits.withDefaults()
+	memory := lang.ML(limits.ML) * 1024 * 1024
+
+	p, pooled := e.pool(lang)
+	if !pooled {
+		// the build is run where it is, the container is created with the limits of the run
+		c, err := e.createContainer(ctx, lang, fmt.Sprintf("%s:%s:ro", workDir, codeDir), memory)
+		if err != nil {
+			return nil, err
+		}
+		defer e.removeContainer(context.WithoutCancel(ctx), c)
+
+		metrics, _, err := e.execute(ctx, lang, limits, c, workDir, codeDir, in)
+		return metrics, err
+	}
+
+	c, err := e.acquire(ctx, lang, p)
 	if err != nil {
-		return pkg.Wrap(pkg.ErrInternal, err, op, "failed to create container")
+		return nil, err
+	}
+
+	healthy := false
+	defer func() {
+		e.release(context.WithoutCancel(ctx), lang, p, c, healthy)
+	}()
+
+	runDir, runPath, err := c.newRun()
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to create run dir")
 	}
 
-	containerID := resp.ID
-	defer e.dockerClient.ContainerRemove(ctx, containerID, container.RemoveOptions{})
+	err = copyFiles(workDir, runDir)
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to copy build")
+	}
 
-	conn, err := e.dockerClient.ContainerAttach(ctx, containerID, container.AttachOptions{
-		Stream: true,
-		Stdin:  true,
-		Stdout: true,
-		Stderr: true,
+	_, err = e.dockerClient.ContainerUpdate(ctx, c.id, container.UpdateConfig{
+		Resources: container.Resources{Memory: memory, MemorySwap: memory},
 	})
 	if err != nil {
-		return pkg.Wrap(pkg.ErrInternal, err, op, "failed to attach to container")
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to update container limits")
 	}
-	defer conn.Close()
 
-	err = e.dockerClient.ContainerStart(ctx, containerID, container.StartOptions{})
+	var metrics *Metrics
+	metrics, healthy, err = e.execute(ctx, lang, limits, c, workDir, runPath, in)
+	return metrics, err
+}
+
+// execute runs the build found at codePath inside the container, output.txt and stderr.txt
+// are written to workDir. healthy tells whether the container is fit for another run.
+func (e *DockerExecutor) execute(
+	ctx context.Context,
+	lang *Language,
+	limits Limits,
+	c *execContainer,
+	workDir string,
+	codePath string,
+	in io.Reader,
+) (*Metrics, bool, error) {
+	const op = "DockerExecutor.execute"
+
+	// output files are kept, so that the caller can still inspect them
+	outputFile, err := os.Create(filepath.Join(workDir, "output.txt"))
 	if err != nil {
-		return pkg.Wrap(pkg.ErrInternal, err, op, "failed to start container")
+		return nil, false, pkg.Wrap(pkg.ErrInternal, err, op, "failed to create output file")
+	}
+	defer outputFile.Close()
+
+	stderrFile, err := os.Create(filepath.Join(workDir, "stderr.txt"))
+	if err != nil {
+		return nil, false, pkg.Wrap(pkg.ErrInternal, err, op, "failed to create stderr file")
+	}
+	defer stderrFile.Close()
+
+	exec, err := e.dockerClient.ContainerExecCreate(ctx, c.id, container.ExecOptions{
+		User:         "1000:1000",
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+		WorkingDir:   codePath,
+		Cmd:          executeCMD(lang, limits, codePath),
+	})
+	if err != nil {
+		return nil, false, pkg.Wrap(pkg.ErrInternal, err, op, "failed to create exec")
+	}
+
+	err = c.stats.begin(ctx)
+	if err != nil {
+		return nil, false, pkg.Wrap(pkg.ErrInternal, err, op, "failed to start measuring")
 	}
 
-	_, err = io.Copy(conn.Conn, in)
+	before, err := c.stats.usage(ctx)
 	if err != nil {
-		return pkg.Wrap(pkg.ErrInternal, err, op, "failed to write to container")
+		return nil, false, pkg.Wrap(pkg.ErrInternal, err, op, "failed to read container stats")
 	}
 
-	err = conn.CloseWrite()
+	start := time.Now()
+
+	// CPU time is limited with ulimit, the wall time limit catches solutions
+	// that sleep, wait for input or do not read the input they are given
+	wall := time.NewTimer(time.Duration(lang.TL(limits.TL)*wallTimeFactor) * time.Millisecond)
+	defer wall.Stop()
+
+	conn, err := e.dockerClient.ContainerExecAttach(ctx, exec.ID, container.ExecAttachOptions{})
 	if err != nil {
-		return pkg.Wrap(pkg.ErrInternal, err, op, "failed to close write to container")
+		return nil, false, pkg.Wrap(pkg.ErrInternal, err, op, "failed to attach to exec")
 	}
+	defer conn.Close()
+
+	stdout := newLimitedWriter(outputFile, limits.OL)
+	stderr := newStderrWriter(stderrFile)
 
-	var stdoutBuf, stderrBuf bytes.Buffer
-	outputDone := make(chan error)
+	outputDone := make(chan error, 1)
 	go func() {
-		_, err := stdcopy.StdCopy(&stdoutBuf, &stderrBuf, conn.Reader)
+		_, err := stdcopy.StdCopy(stdout, stderr, conn.Reader)
 		outputDone <- err
 	}()
 
-	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Second) // FIXME: make this configurable
-	defer cancel()
+	// the input is written while the program runs, the writes block until it reads
+	input := &inputReader{r: in}
+	inputDone := make(chan struct{})
+	go func() {
+		defer close(inputDone)
+		_, err := io.Copy(conn.Conn, input)
+		if err == nil {
+			_ = conn.CloseWrite()
+		}
+	}()
+
+	// a killed container is removed on release,
+	// its stats are read before, they are gone with the container
+	var after cgroupUsage
+	var verdict error
 
-	statusCh, errCh := e.dockerClient.ContainerWait(timeoutCtx, containerID, container.WaitConditionNotRunning)
 	select {
-	case err := <-errCh:
+	case err = <-outputDone:
+	case <-wall.C:
+		after, err = e.kill(ctx, c)
 		if err != nil {
-			err = errors.Join(e.dockerClient.ContainerKill(ctx, containerID, "SIGKILL"), err)
-			return pkg.Wrap(pkg.ErrInternal, err, op, "failed to wait container")
+			return nil, false, pkg.Wrap(pkg.ErrInternal, err, op, "failed to kill container")
 		}
-	case status := <-statusCh:
-		if status.StatusCode != 0 {
-			err = fmt.Errorf("non-zero exit status: %d, failed to run code: %s",
-				status.StatusCode, stderrBuf.String())
-			return pkg.Wrap(RuntimeErr, err, op, "failed to run code")
+
+		verdict = pkg.Wrap(TimeLimitExceededErr, nil, op, "wall time limit exceeded")
+		if after.since(before).cpu.Milliseconds() < lang.TL(limits.TL) {
+			verdict = pkg.Wrap(IdlenessLimitExceededErr, nil, op, "idleness limit exceeded")
+		}
+		err = <-outputDone
+	case <-stdout.exceeded:
+		after, err = e.kill(ctx, c)
+		if err != nil {
+			return nil, false, pkg.Wrap(pkg.ErrInternal, err, op, "failed to kill container")
 		}
+		verdict = pkg.Wrap(OutputLimitExceededErr, nil, op, "output limit exceeded")
+		err = <-outputDone
+	case <-ctx.Done():
+		return nil, false, pkg.Wrap(pkg.ErrInternal, ctx.Err(), op, "canceled")
+	}
+	if err != nil && verdict == nil {
+		return nil, false, pkg.Wrap(pkg.ErrInternal, err, op, "failed to read logs")
 	}
 
-	err = <-outputDone
-	if err != nil {
-		return pkg.Wrap(pkg.ErrInternal, err, op, "failed to read logs")
+	elapsed := time.Since(start)
+
+	// closing the connection stops writing the input the program has left unread
+	conn.Close()
+	<-inputDone
+	if input.err != nil {
+		return nil, false, pkg.Wrap(pkg.ErrInternal, input.err, op, "failed to read input")
 	}
 
-	timeFilePath := filepath.Join(workDir, "time.txt")
-	timeFile, err := os.Create(timeFilePath)
+	err = stderr.Flush()
 	if err != nil {
-		return pkg.Wrap(pkg.ErrInternal, err, op, "failed to create time file")
+		return nil, false, pkg.Wrap(pkg.ErrInternal, err, op, "failed to write stderr file")
 	}
-	defer timeFile.Close()
 
-	_, err = io.Copy(timeFile, &stderrBuf)
-	if err != nil {
-		return pkg.Wrap(pkg.ErrInternal, err, op, "failed to write time file")
+	if verdict != nil {
+		return after.since(before).metrics(elapsed, 0), false, verdict
 	}
 
-	outputFilePath := filepath.Join(workDir, "output.txt")
-	outputFile, err := os.Create(outputFilePath)
+	exitCode, err := e.execExitCode(ctx, exec.ID)
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %v", err)
+		return nil, false, pkg.Wrap(pkg.ErrInternal, err, op, "failed to inspect exec")
 	}
-	defer outputFile.Close()
 
-	_, err = io.Copy(outputFile, &stdoutBuf)
+	after, err = c.stats.usage(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to write output file: %v", err)
+		return nil, false, pkg.Wrap(pkg.ErrInternal, err, op, "failed to read container stats")
 	}
 
-	return nil
+	usage := after.since(before)
+	metrics := usage.metrics(elapsed, exitCode)
+
+	// the limit might have been hit right before the exit
+	if stdout.Exceeded() {
+		return metrics, false, pkg.Wrap(OutputLimitExceededErr, nil, op, "output limit exceeded")
+	}
+
+	// the memory cgroup might have killed something else than the program as well,
+	// the container is recycled to start over clean
+	oomKilled := usage.oomKills > 0
+
+	return metrics, !oomKilled, exitVerdict(op, exitCode, oomKilled)
+}
+
+// inputReader remembers the error of reading the input, the errors of writing it
+// only mean the program has finished without reading all of it
+type inputReader struct {
+	r   io.Reader
+	err error
+}
+
+func (r *inputReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		r.err = err
+	}
+	return n, err
+}
+
+// the signals of linux, the programs run in linux containers whatever the host is
+const (
+	sigKill = 9
+	sigXCPU = 24
+)
+
+// exitVerdict maps how the program finished to a verdict
+func exitVerdict(op string, statusCode int64, oomKilled bool) error {
+	if oomKilled {
+		return pkg.Wrap(MemoryLimitExceededErr, nil, op, "killed by the memory cgroup")
+	}
+
+	switch statusCode {
+	case 0:
+		return nil
+	case 128 + sigXCPU, 128 + sigKill:
+		// the soft CPU limit sends SIGXCPU and the hard one SIGKILL,
+		// SIGKILL of the memory cgroup has already been ruled out
+		return pkg.Wrap(TimeLimitExceededErr, nil, op, "cpu time limit exceeded")
+	}
+
+	err := fmt.Errorf("non-zero exit status: %d", statusCode)
+	return pkg.Wrap(RuntimeErr, err, op, "failed to run code")
 }
@@ -0,0 +1,139 @@
+package tester_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/Vyacheslav1557/tester/pkg/tester"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/stretchr/testify/require"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// BenchmarkDockerExecutor compares the container created and attached per run, the way
+// the executor used to run tests, with the executor with and without warm containers.
+// It needs docker and the image of the Python language:
+//
+//	go test ./pkg/tester -run '^$' -bench DockerExecutor
+func BenchmarkDockerExecutor(b *testing.B) {
+	ctx := context.Background()
+
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	require.NoError(b, err)
+
+	if _, err := cli.Ping(ctx); err != nil {
+		b.Skipf("docker is not available: %v", err)
+	}
+
+	python := tester.GetConfig(models.Python)
+	if _, _, err := cli.ImageInspectWithRaw(ctx, python.Image); err != nil {
+		b.Skipf("image %s is not available: %v", python.Image, err)
+	}
+
+	limits := tester.Limits{TL: 1000, ML: 256}
+
+	workDir := b.TempDir()
+	require.NoError(b, os.WriteFile(filepath.Join(workDir, "solution"), []byte("print(input())\n"), 0644))
+
+	b.Run("create per run", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			require.NoError(b, runContainer(ctx, cli, python.Image, python.ExecuteCMD(limits), workDir, "42\n"))
+		}
+	})
+
+	for _, bm := range []struct {
+		name string
+		pool tester.WarmPool
+	}{
+		{name: "container"},
+		{name: "warm", pool: tester.WarmPool{Size: 1, MaxUses: 1000}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			lang := *python
+			lang.WarmPool = bm.pool
+
+			executor := tester.NewDockerExecutor(cli, b.TempDir())
+			defer executor.Close(ctx)
+
+			require.NoError(b, executor.Warm(ctx, []*tester.Language{&lang}))
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := executor.Execute(ctx, &lang, limits, workDir, strings.NewReader("42\n"))
+				require.NoError(b, err)
+			}
+		})
+	}
+}
+
+// runContainer runs cmd in a container of its own with workDir at /code,
+// the container is created, attached to, started and removed
+func runContainer(ctx context.Context, cli *client.Client, image string, cmd []string, workDir, input string) error {
+	resp, err := cli.ContainerCreate(ctx,
+		&container.Config{
+			Image:           image,
+			Cmd:             cmd,
+			OpenStdin:       true,
+			StdinOnce:       true,
+			NetworkDisabled: true,
+			User:            "1000:1000",
+		},
+		&container.HostConfig{
+			Binds: []string{workDir + ":/code:ro"},
+		},
+		nil,
+		nil,
+		"",
+	)
+	if err != nil {
+		return err
+	}
+	defer cli.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true})
+
+	conn, err := cli.ContainerAttach(ctx, resp.ID, container.AttachOptions{
+		Stream: true,
+		Stdin:  true,
+		Stdout: true,
+		Stderr: true,
+	})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	err = cli.ContainerStart(ctx, resp.ID, container.StartOptions{})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(conn.Conn, input)
+	if err != nil {
+		return err
+	}
+	err = conn.CloseWrite()
+	if err != nil {
+		return err
+	}
+
+	_, err = stdcopy.StdCopy(io.Discard, io.Discard, conn.Reader)
+	if err != nil {
+		return err
+	}
+
+	statusCh, errCh := cli.ContainerWait(ctx, resp.ID, container.WaitConditionNotRunning)
+	select {
+	case err := <-errCh:
+		return err
+	case status := <-statusCh:
+		if status.StatusCode != 0 {
+			return fmt.Errorf("exit status %d", status.StatusCode)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,7 @@
+package tester
+
+func (l *Language) ForSource(source []byte) *Language {
+	return l.forSource(source)
+}
+
+var Relocate = relocate
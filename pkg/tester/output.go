@@ -0,0 +1,65 @@
+package tester
+
+import (
+	"io"
+	"sync"
+)
+
+const (
+	maxStderr     = 64 * 1024 // 64 KB, the program's own stderr
+	maxCompileLog = 64 * 1024 // 64 KB, stdout and stderr of the compiler
+)
+
+// limitedWriter writes up to n bytes and silently drops the rest,
+// so that the container output can still be drained
+type limitedWriter struct {
+	w        io.Writer
+	n        int64
+	once     sync.Once
+	exceeded chan struct{} // closed once the limit is exceeded
+}
+
+func newLimitedWriter(w io.Writer, n int64) *limitedWriter {
+	return &limitedWriter{w: w, n: n, exceeded: make(chan struct{})}
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > l.n {
+		l.once.Do(func() { close(l.exceeded) })
+
+		_, err := l.w.Write(p[:l.n])
+		l.n = 0
+		return len(p), err
+	}
+
+	l.n -= int64(len(p))
+	return l.w.Write(p)
+}
+
+// Exceeded reports whether anything was dropped
+func (l *limitedWriter) Exceeded() bool {
+	select {
+	case <-l.exceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+// stderrWriter keeps the head of stderr, Flush marks it as truncated if anything was dropped
+type stderrWriter struct {
+	*limitedWriter
+}
+
+func newStderrWriter(w io.Writer) *stderrWriter {
+	return &stderrWriter{newLimitedWriter(w, maxStderr)}
+}
+
+func (s *stderrWriter) Flush() error {
+	if !s.Exceeded() {
+		return nil
+	}
+
+	_, err := s.w.Write([]byte("\n... (truncated)\n"))
+	return err
+}
@@ -0,0 +1,138 @@
+package tester
+
+import (
+	"context"
+	"errors"
+	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	maxRunStdout = 64 * 1024 // 64 KB
+	maxRunStderr = 16 * 1024 // 16 KB
+)
+
+// RunResult is the outcome of a custom invocation.
+// State is zero when the program ran within the limits and exited normally.
+type RunResult struct {
+	State    models.State
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	Metrics  *Metrics
+}
+
+// Run compiles the solution and executes it once on the given input.
+// Unlike Test, nothing is compared and the run goes through a separate pool.
+func (t *Tester) Run(ctx context.Context, s Solution, in io.Reader, tl, ml int64) (*RunResult, error) {
+	const op = "Tester.Run"
+
+	lang := GetConfig(s.Lang())
+	if lang == nil {
+		return nil, pkg.Wrap(pkg.ErrBadInput, nil, op, "unknown language")
+	}
+	lang, err := lang.forSolution(s)
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrBadInput, err, op, "failed to resolve build")
+	}
+
+	workDir, err := os.MkdirTemp("", "run")
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to create work dir")
+	}
+	defer os.RemoveAll(workDir)
+
+	_, err = t.prepareSource(s, lang, workDir)
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to prepare source")
+	}
+
+	if len(lang.Compile) > 0 {
+		err = t.compiler.Compile(ctx, lang, workDir)
+		if err != nil {
+			if errors.Is(err, CompilationErr) {
+				return &RunResult{
+					State:  models.GotCE,
+					Stderr: truncate(t.sanitize(CompileLog(err), workDir), maxRunStderr),
+				}, nil
+			}
+			return nil, err
+		}
+	}
+
+	testDir, err := os.MkdirTemp("", "run")
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to create test dir")
+	}
+	defer os.RemoveAll(testDir)
+
+	_, err = t.prepareBuild(testDir, filepath.Join(workDir, "solution"))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RunResult{}
+
+	metrics, err := t.execute(ctx, t.runPool, schedulingClass{}, lang, Limits{TL: tl, ML: ml}, testDir, in)
+	if err != nil {
+		var stateErr *StateErr
+		if !errors.As(err, &stateErr) {
+			return nil, err
+		}
+		result.State = stateErr.State
+	}
+	if metrics == nil {
+		metrics = &Metrics{}
+	}
+
+	stdout, err := readTruncated(filepath.Join(testDir, "output.txt"), maxRunStdout)
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to read output")
+	}
+	result.Stdout = stdout
+
+	stderr, err := readTruncated(filepath.Join(testDir, "stderr.txt"), maxRunStderr)
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to read stderr")
+	}
+	result.Stderr = t.sanitize(stderr, testDir)
+
+	result.Metrics = metrics
+	result.ExitCode = metrics.ExitCode
+
+	if result.State == 0 {
+		if metrics.CPUTime.Milliseconds() > lang.TL(tl) {
+			result.State = models.GotTL
+		} else if metrics.Memory >= lang.ML(ml)*1024 {
+			result.State = models.GotML
+		}
+	}
+
+	return result, nil
+}
+
+func readTruncated(path string, n int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	b, err := io.ReadAll(io.LimitReader(f, n+1))
+	if err != nil {
+		return "", err
+	}
+
+	return truncate(string(b), int(n)), nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "") + "\n... (truncated)"
+}
@@ -0,0 +1,435 @@
+package tester
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"golang.org/x/sys/unix"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// sandboxInitArg is argv[0] of the judge re-executed as the sandbox init process
+const sandboxInitArg = "tester-sandbox-init"
+
+const (
+	sandboxUid  = 1000
+	sandboxGid  = 1000
+	sandboxPids = 100
+
+	// exit status of the init process if the sandbox could not be set up,
+	// the reason is written to the error pipe
+	sandboxFailure = 125
+)
+
+var sandboxEnv = []string{
+	"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
+	"HOME=/tmp",
+	"LANG=C.UTF-8",
+}
+
+// NativeExecutor runs solutions directly with Linux namespaces, rlimits,
+// a seccomp filter and a cgroup v2 subtree per run, without a container runtime.
+//
+// rootDir holds a prepared rootfs for every language in <rootDir>/<language id>,
+// it is mounted read-only with the work dir at /code. cgroupDir is a cgroup v2
+// directory delegated to the judge, which itself must live outside of it.
+type NativeExecutor struct {
+	rootDir   string
+	cgroupDir string
+	seq       atomic.Int64
+}
+
+func NewNativeExecutor(rootDir, cgroupDir string) (*NativeExecutor, error) {
+	err := os.WriteFile(filepath.Join(cgroupDir, "cgroup.subtree_control"), []byte(cgroupControllers), 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable cgroup controllers: %w", err)
+	}
+
+	return &NativeExecutor{rootDir: rootDir, cgroupDir: cgroupDir}, nil
+}
+
+// sandboxConfig is passed to the init process
+type sandboxConfig struct {
+	Rootfs   string   `json:"rootfs"`
+	WorkDir  string   `json:"work_dir"`
+	Writable bool     `json:"writable"`
+	Cmd      []string `json:"cmd"`
+	CPU      int64    `json:"cpu"` // s
+}
+
+type sandboxRun struct {
+	config sandboxConfig
+	memory int64 // bytes
+	wall   time.Duration
+
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	kill <-chan struct{} // kills the run when closed, e.g. on too much output
+}
+
+type sandboxResult struct {
+	exitCode int64
+	wall     time.Duration
+	usage    cgroupUsage
+	timedOut bool
+	killed   bool
+}
+
+func (r *sandboxResult) metrics() *Metrics {
+	return r.usage.metrics(r.wall, r.exitCode)
+}
+
+func (e *NativeExecutor) run(ctx context.Context, r sandboxRun) (*sandboxResult, error) {
+	const op = "NativeExecutor.run"
+
+	cg, err := newCgroup(
+		filepath.Join(e.cgroupDir, fmt.Sprintf("run-%d-%d", os.Getpid(), e.seq.Add(1))),
+		cgroupLimits{memory: r.memory, pids: sandboxPids},
+	)
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to create cgroup")
+	}
+	defer cg.remove()
+
+	config, err := json.Marshal(r.config)
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to encode sandbox config")
+	}
+
+	errR, errW, err := os.Pipe()
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to create pipe")
+	}
+	defer errR.Close()
+
+	cmd := &exec.Cmd{
+		Path:   "/proc/self/exe",
+		Args:   []string{sandboxInitArg, string(config)},
+		Env:    sandboxEnv,
+		Stdin:  r.stdin,
+		Stdout: r.stdout,
+		Stderr: r.stderr,
+		// fd 3 and fd 4 of the init process
+		ExtraFiles: []*os.File{cg.program, errW},
+		SysProcAttr: &syscall.SysProcAttr{
+			Cloneflags: syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWNET |
+				syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS,
+			UseCgroupFD: true,
+			CgroupFD:    int(cg.init.Fd()),
+			Pdeathsig:   syscall.SIGKILL,
+		},
+		WaitDelay: time.Second,
+	}
+
+	start := time.Now()
+	err = cmd.Start()
+	errW.Close()
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to start sandbox")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	timer := time.NewTimer(r.wall)
+	defer timer.Stop()
+
+	res := &sandboxResult{}
+
+	select {
+	case err = <-done:
+	case <-timer.C:
+		res.timedOut = true
+	case <-r.kill:
+		res.killed = true
+	case <-ctx.Done():
+		err = cg.kill()
+		<-done
+		return nil, pkg.Wrap(pkg.ErrInternal, errors.Join(err, ctx.Err()), op, "canceled")
+	}
+
+	if res.timedOut || res.killed {
+		if err = cg.kill(); err != nil {
+			return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to kill sandbox")
+		}
+		err = <-done
+	}
+	res.wall = time.Since(start)
+
+	setupErr, _ := io.ReadAll(errR)
+	if len(setupErr) > 0 {
+		return nil, pkg.Wrap(pkg.ErrInternal, errors.New(string(setupErr)), op, "failed to set up sandbox")
+	}
+
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to wait sandbox")
+	}
+	res.exitCode = int64(cmd.ProcessState.ExitCode())
+
+	res.usage, err = cg.stats()
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to read cgroup stats")
+	}
+
+	return res, nil
+}
+
+func (e *NativeExecutor) rootfs(lang *Language) string {
+	return filepath.Join(e.rootDir, strconv.Itoa(int(lang.Id)))
+}
+
+func (e *NativeExecutor) Compile(ctx context.Context, lang *Language, workDir string) error {
+	const op = "NativeExecutor.Compile"
+
+	err := grantWorkDir(workDir)
+	if err != nil {
+		return pkg.Wrap(pkg.ErrInternal, err, op, "failed to hand the work dir over to the sandbox")
+	}
+
+	var compileLog strings.Builder
+	output := newLimitedWriter(&compileLog, maxCompileLog)
+
+	res, err := e.run(ctx, sandboxRun{
+		config: sandboxConfig{
+			Rootfs:   e.rootfs(lang),
+			WorkDir:  workDir,
+			Writable: true,
+			Cmd:      lang.Compile,
+			CPU:      int64(lang.CompileTimeout.Seconds()),
+		},
+		memory: lang.CompileML(),
+		wall:   lang.CompileTimeout,
+		stdout: output,
+		stderr: output,
+	})
+	if err != nil {
+		return err
+	}
+
+	if res.timedOut {
+		return compilationError(op, "compilation timed out")
+	}
+
+	// warnings are fine as long as the compiler succeeds
+	if res.exitCode != 0 {
+		return compilationError(op, compileLog.String())
+	}
+
+	return nil
+}
+
+func (e *NativeExecutor) Execute(
+	ctx context.Context,
+	lang *Language,
+	limits Limits,
+	workDir string,
+	in io.Reader,
+) (*Metrics, error) {
+	const op = "NativeExecutor.Execute"
+
+	limits = limits.withDefaults()
+
+	err := grantWorkDir(workDir)
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to hand the work dir over to the sandbox")
+	}
+
+	// output files are kept, so that the caller can still inspect them
+	outputFile, err := os.Create(filepath.Join(workDir, "output.txt"))
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to create output file")
+	}
+	defer outputFile.Close()
+
+	stderrFile, err := os.Create(filepath.Join(workDir, "stderr.txt"))
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to create stderr file")
+	}
+	defer stderrFile.Close()
+
+	stdout := newLimitedWriter(outputFile, limits.OL)
+	stderr := newStderrWriter(stderrFile)
+
+	res, err := e.run(ctx, sandboxRun{
+		config: sandboxConfig{
+			Rootfs:  e.rootfs(lang),
+			WorkDir: workDir,
+			Cmd:     lang.ExecuteCMD(limits),
+			CPU:     lang.CPULimit(limits),
+		},
+		memory: lang.ML(limits.ML) * 1024 * 1024,
+		wall:   time.Duration(lang.TL(limits.TL)*wallTimeFactor) * time.Millisecond,
+		stdin:  in,
+		stdout: stdout,
+		stderr: stderr,
+		kill:   stdout.exceeded,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = stderr.Flush()
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to write stderr file")
+	}
+
+	metrics := res.metrics()
+
+	switch {
+	case res.killed || stdout.Exceeded():
+		return metrics, pkg.Wrap(OutputLimitExceededErr, nil, op, "output limit exceeded")
+	case res.timedOut && metrics.CPUTime.Milliseconds() < lang.TL(limits.TL):
+		return metrics, pkg.Wrap(IdlenessLimitExceededErr, nil, op, "idleness limit exceeded")
+	case res.timedOut:
+		return metrics, pkg.Wrap(TimeLimitExceededErr, nil, op, "wall time limit exceeded")
+	}
+
+	return metrics, exitVerdict(op, res.exitCode, res.usage.oomKills > 0)
+}
+
+// grantWorkDir makes the sandbox user the owner of the work dir and its files,
+// they are created private to the judge, which the program does not run as
+func grantWorkDir(workDir string) error {
+	return filepath.WalkDir(workDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(path, sandboxUid, sandboxGid)
+	})
+}
+
+// SandboxInit has to be called first thing in main. In the re-executed
+// sandbox init process it never returns, otherwise it does nothing.
+func SandboxInit() {
+	if len(os.Args) != 2 || os.Args[0] != sandboxInitArg {
+		return
+	}
+
+	code, err := sandboxInit(os.Args[1])
+	if err != nil {
+		errPipe := os.NewFile(4, "errors")
+		fmt.Fprint(errPipe, err.Error())
+		os.Exit(sandboxFailure)
+	}
+
+	os.Exit(code)
+}
+
+// sandboxInit runs as pid 1 of fresh namespaces in the init cgroup.
+// It builds the filesystem, drops privileges for the program and reports
+// its exit status, 128+n if it was killed by signal n.
+func sandboxInit(data string) (int, error) {
+	// the program must not inherit the cgroup and the error pipe
+	unix.CloseOnExec(3)
+	unix.CloseOnExec(4)
+
+	var config sandboxConfig
+	if err := json.Unmarshal([]byte(data), &config); err != nil {
+		return 0, fmt.Errorf("failed to decode config: %w", err)
+	}
+
+	if len(config.Cmd) == 0 {
+		return 0, errors.New("no command")
+	}
+
+	if err := setupRootfs(config); err != nil {
+		return 0, err
+	}
+
+	hard := config.CPU + 1
+	for resource, limit := range map[int]unix.Rlimit{
+		unix.RLIMIT_CPU:    {Cur: uint64(config.CPU), Max: uint64(hard)},
+		unix.RLIMIT_CORE:   {Cur: 0, Max: 0},
+		unix.RLIMIT_NOFILE: {Cur: 256, Max: 256},
+		unix.RLIMIT_STACK:  {Cur: unix.RLIM_INFINITY, Max: unix.RLIM_INFINITY},
+	} {
+		if err := unix.Setrlimit(resource, &limit); err != nil {
+			return 0, fmt.Errorf("failed to set rlimit %d: %w", resource, err)
+		}
+	}
+
+	if err := installSeccomp(); err != nil {
+		return 0, err
+	}
+
+	cmd := exec.Command(config.Cmd[0], config.Cmd[1:]...)
+	cmd.Env = sandboxEnv
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Credential:  &syscall.Credential{Uid: sandboxUid, Gid: sandboxGid},
+		UseCgroupFD: true,
+		CgroupFD:    3,
+		Pdeathsig:   syscall.SIGKILL,
+	}
+
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return 0, fmt.Errorf("failed to run %s: %w", config.Cmd[0], err)
+	}
+
+	status := cmd.ProcessState.Sys().(syscall.WaitStatus)
+	if status.Signaled() {
+		return 128 + int(status.Signal()), nil
+	}
+
+	return status.ExitStatus(), nil
+}
+
+// setupRootfs mounts the rootfs read-only with the work dir at /code,
+// a private /proc and /tmp, and chroots into it
+func setupRootfs(config sandboxConfig) error {
+	root := config.Rootfs
+	code := filepath.Join(root, "code")
+
+	codeFlags := uintptr(unix.MS_BIND | unix.MS_REMOUNT | unix.MS_NOSUID | unix.MS_NODEV)
+	if !config.Writable {
+		codeFlags |= unix.MS_RDONLY
+	}
+
+	mounts := []struct {
+		source, target, fstype string
+		flags                  uintptr
+		data                   string
+	}{
+		// nothing propagates back to the host
+		{"", "/", "", unix.MS_REC | unix.MS_PRIVATE, ""},
+		{root, root, "", unix.MS_BIND | unix.MS_REC, ""},
+		{config.WorkDir, code, "", unix.MS_BIND, ""},
+		{"", code, "", codeFlags, ""},
+		{"proc", filepath.Join(root, "proc"), "proc", unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC, ""},
+		{"tmpfs", filepath.Join(root, "tmp"), "tmpfs", unix.MS_NOSUID | unix.MS_NODEV, "size=64m,mode=1777"},
+		{"", root, "", unix.MS_BIND | unix.MS_REMOUNT | unix.MS_RDONLY | unix.MS_NOSUID | unix.MS_NODEV, ""},
+	}
+
+	for _, m := range mounts {
+		if err := unix.Mount(m.source, m.target, m.fstype, m.flags, m.data); err != nil {
+			return fmt.Errorf("failed to mount %s: %w", m.target, err)
+		}
+	}
+
+	if err := unix.Chroot(root); err != nil {
+		return fmt.Errorf("failed to chroot: %w", err)
+	}
+
+	return os.Chdir("/code")
+}
@@ -0,0 +1,67 @@
+package tester_test
+
+import (
+	"context"
+	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/Vyacheslav1557/tester/pkg/tester"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// the native executor re-executes the test binary as the init process of its sandboxes
+func TestMain(m *testing.M) {
+	tester.SandboxInit()
+	os.Exit(m.Run())
+}
+
+// TestNativeExecutor compiles and runs a C++ program in the sandbox. It needs root,
+// a rootfs of the C++ language and a delegated cgroup, see README:
+//
+//	TESTER_SANDBOX_ROOT=/var/lib/tester/rootfs TESTER_SANDBOX_CGROUP=/sys/fs/cgroup/tester \
+//		go test ./pkg/tester -run NativeExecutor
+func TestNativeExecutor(t *testing.T) {
+	rootDir, cgroupDir := os.Getenv("TESTER_SANDBOX_ROOT"), os.Getenv("TESTER_SANDBOX_CGROUP")
+	if rootDir == "" || cgroupDir == "" {
+		t.Skip("TESTER_SANDBOX_ROOT and TESTER_SANDBOX_CGROUP are not set")
+	}
+	if os.Getuid() != 0 {
+		t.Skip("the sandbox needs root")
+	}
+
+	executor, err := tester.NewNativeExecutor(rootDir, cgroupDir)
+	require.NoError(t, err)
+
+	cpp := tester.GetConfig(models.Cpp)
+	require.NotNil(t, cpp)
+
+	// the work dir and the source are private to the judge, like the ones of the tester
+	workDir := t.TempDir()
+	source := `#include <cstdio>
+#include <vector>
+
+int main() {
+	std::vector<char> v(64 << 20, 1);
+	int x;
+	scanf("%d", &x);
+	printf("%d\n", x + v[12345]);
+	return 3;
+}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(workDir, cpp.SourceFile), []byte(source), 0600))
+
+	ctx := context.Background()
+
+	require.NoError(t, executor.Compile(ctx, cpp, workDir))
+
+	metrics, err := executor.Execute(ctx, cpp, tester.Limits{TL: 1000, ML: 256}, workDir, strings.NewReader("41\n"))
+	require.ErrorIs(t, err, tester.RuntimeErr)
+	require.Equal(t, 3, metrics.ExitCode)
+	require.GreaterOrEqual(t, metrics.Memory, int64(64*1024), "KB")
+
+	output, err := os.ReadFile(filepath.Join(workDir, "output.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "42\n", string(output))
+}
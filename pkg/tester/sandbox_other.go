@@ -0,0 +1,34 @@
+//go:build !linux
+
+package tester
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var errNativeUnsupported = errors.New("native executor is only supported on linux")
+
+// NativeExecutor needs linux namespaces and cgroups, see sandbox_linux.go
+type NativeExecutor struct{}
+
+func NewNativeExecutor(rootDir, cgroupDir string) (*NativeExecutor, error) {
+	return nil, errNativeUnsupported
+}
+
+func (e *NativeExecutor) Compile(ctx context.Context, lang *Language, workDir string) error {
+	return errNativeUnsupported
+}
+
+func (e *NativeExecutor) Execute(
+	ctx context.Context,
+	lang *Language,
+	limits Limits,
+	workDir string,
+	in io.Reader,
+) (*Metrics, error) {
+	return nil, errNativeUnsupported
+}
+
+func SandboxInit() {}
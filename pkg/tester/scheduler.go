@@ -0,0 +1,207 @@
+package tester
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Priority is the scheduling class of a run, the lower ones go first
+type Priority int
+
+const (
+	PriorityContest  Priority = iota // live contests
+	PriorityHomework                 // homework with a deadline
+	PriorityPractice                 // practice and problem preparation
+	PriorityRejudge                  // rejudges wait for everything else
+
+	priorities = iota
+)
+
+var priorityNames = [priorities]string{"contest", "homework", "practice", "rejudge"}
+
+func (p Priority) String() string {
+	if p < 0 || p >= priorities {
+		return "unknown"
+	}
+	return priorityNames[p]
+}
+
+// QueueStats is the state of a scheduling class
+type QueueStats struct {
+	Priority    Priority
+	Depth       int           // tasks waiting for a worker
+	OldestWait  time.Duration // of the task waiting the longest
+	AverageWait time.Duration // of the tasks started so far
+}
+
+var errSchedulerClosed = errors.New("scheduler is closed")
+
+// Scheduler runs tasks on n workers like Pool does, but the tasks of a higher
+// priority always go first and the users of a priority take turns, so that
+// a user with lots of tasks does not delay everybody else.
+type Scheduler[T interface{}] struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	classes [priorities]schedulerClass[T]
+	closed  bool
+
+	wg       sync.WaitGroup
+	f        func(T)
+	classify func(T) (Priority, int32)
+}
+
+type schedulerClass[T interface{}] struct {
+	users  []int32 // with queued tasks, in the order of their turns
+	queues map[int32][]scheduledTask[T]
+	depth  int
+
+	started int64
+	waited  time.Duration
+}
+
+type scheduledTask[T interface{}] struct {
+	task     T
+	queuedAt time.Time
+}
+
+// NewScheduler starts n workers, classify tells the priority and the user of a task
+func NewScheduler[T interface{}](n int, f func(T), classify func(T) (Priority, int32)) *Scheduler[T] {
+	s := &Scheduler[T]{
+		f:        f,
+		classify: classify,
+	}
+	s.cond = sync.NewCond(&s.mu)
+
+	for i := range s.classes {
+		s.classes[i].queues = make(map[int32][]scheduledTask[T])
+	}
+
+	s.wg.Add(n)
+	for i := 0; i < n; i++ {
+		go s.newWorker()
+	}
+
+	return s
+}
+
+func (s *Scheduler[T]) newWorker() {
+	defer s.wg.Done()
+
+	for {
+		task, ok := s.next()
+		if !ok {
+			return
+		}
+		s.f(task)
+	}
+}
+
+// next waits for a task, it reports false once the scheduler is closed and drained
+func (s *Scheduler[T]) next() (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		for i := range s.classes {
+			c := &s.classes[i]
+			if c.depth == 0 {
+				continue
+			}
+
+			user := c.users[0]
+			queue := c.queues[user]
+			item := queue[0]
+
+			c.users = c.users[1:]
+			if len(queue) > 1 {
+				c.queues[user] = queue[1:]
+				c.users = append(c.users, user)
+			} else {
+				delete(c.queues, user)
+			}
+
+			c.depth--
+			c.started++
+			c.waited += time.Since(item.queuedAt)
+
+			return item.task, true
+		}
+
+		if s.closed {
+			var zero T
+			return zero, false
+		}
+		s.cond.Wait()
+	}
+}
+
+// Do queues the task, it never blocks
+func (s *Scheduler[T]) Do(ctx context.Context, task T) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	priority, user := s.classify(task)
+	if priority < 0 || priority >= priorities {
+		priority = PriorityPractice
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errSchedulerClosed
+	}
+
+	c := &s.classes[priority]
+	if _, ok := c.queues[user]; !ok {
+		c.users = append(c.users, user)
+	}
+	c.queues[user] = append(c.queues[user], scheduledTask[T]{task: task, queuedAt: time.Now()})
+	c.depth++
+
+	s.cond.Signal()
+
+	return nil
+}
+
+// Stats returns the state of every priority, the highest first
+func (s *Scheduler[T]) Stats() []QueueStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	stats := make([]QueueStats, priorities)
+	for i := range s.classes {
+		c := &s.classes[i]
+
+		stats[i] = QueueStats{
+			Priority: Priority(i),
+			Depth:    c.depth,
+		}
+
+		// the heads of the user queues are the oldest tasks of their users
+		for _, queue := range c.queues {
+			stats[i].OldestWait = max(stats[i].OldestWait, now.Sub(queue[0].queuedAt))
+		}
+
+		if c.started > 0 {
+			stats[i].AverageWait = c.waited / time.Duration(c.started)
+		}
+	}
+
+	return stats
+}
+
+// Close lets the workers finish the queued tasks and waits for them
+func (s *Scheduler[T]) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.cond.Broadcast()
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
@@ -0,0 +1,122 @@
+package tester_test
+
+import (
+	"context"
+	"github.com/Vyacheslav1557/tester/pkg/tester"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+type task struct {
+	priority tester.Priority
+	user     int32
+	name     string
+}
+
+func classify(t task) (tester.Priority, int32) {
+	return t.priority, t.user
+}
+
+// newBlockedScheduler returns a single worker scheduler busy until release is closed,
+// so that the tasks queued meanwhile are ordered by the scheduler alone
+func newBlockedScheduler(t *testing.T) (*tester.Scheduler[task], func() []string) {
+	t.Helper()
+
+	var (
+		mu   sync.Mutex
+		done []string
+	)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	s := tester.NewScheduler[task](1, func(t task) {
+		if t.name == "blocker" {
+			close(started)
+			<-release
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		done = append(done, t.name)
+	}, classify)
+
+	require.NoError(t, s.Do(context.Background(), task{name: "blocker"}))
+	<-started
+
+	return s, func() []string {
+		close(release)
+		s.Close()
+		return done
+	}
+}
+
+func TestScheduler_Priority(t *testing.T) {
+	t.Parallel()
+
+	s, finish := newBlockedScheduler(t)
+
+	ctx := context.Background()
+	require.NoError(t, s.Do(ctx, task{priority: tester.PriorityRejudge, user: 1, name: "rejudge"}))
+	require.NoError(t, s.Do(ctx, task{priority: tester.PriorityPractice, user: 1, name: "practice"}))
+	require.NoError(t, s.Do(ctx, task{priority: tester.PriorityHomework, user: 1, name: "homework"}))
+	require.NoError(t, s.Do(ctx, task{priority: tester.PriorityContest, user: 1, name: "contest"}))
+
+	require.Equal(t, []string{"contest", "homework", "practice", "rejudge"}, finish())
+}
+
+func TestScheduler_RoundRobin(t *testing.T) {
+	t.Parallel()
+
+	s, finish := newBlockedScheduler(t)
+
+	ctx := context.Background()
+	for _, name := range []string{"a1", "a2", "a3"} {
+		require.NoError(t, s.Do(ctx, task{priority: tester.PriorityHomework, user: 1, name: name}))
+	}
+	require.NoError(t, s.Do(ctx, task{priority: tester.PriorityHomework, user: 2, name: "b1"}))
+	require.NoError(t, s.Do(ctx, task{priority: tester.PriorityHomework, user: 3, name: "c1"}))
+	require.NoError(t, s.Do(ctx, task{priority: tester.PriorityHomework, user: 2, name: "b2"}))
+
+	require.Equal(t, []string{"a1", "b1", "c1", "a2", "b2", "a3"}, finish())
+}
+
+func TestScheduler_Stats(t *testing.T) {
+	t.Parallel()
+
+	s, finish := newBlockedScheduler(t)
+
+	ctx := context.Background()
+	require.NoError(t, s.Do(ctx, task{priority: tester.PriorityContest, user: 1, name: "contest"}))
+	require.NoError(t, s.Do(ctx, task{priority: tester.PriorityRejudge, user: 1, name: "rejudge1"}))
+	require.NoError(t, s.Do(ctx, task{priority: tester.PriorityRejudge, user: 2, name: "rejudge2"}))
+
+	time.Sleep(10 * time.Millisecond)
+
+	stats := s.Stats()
+	require.Len(t, stats, 4)
+
+	require.Equal(t, tester.PriorityContest, stats[0].Priority)
+	require.Equal(t, 1, stats[0].Depth)
+	require.Equal(t, 0, stats[1].Depth)
+	require.Equal(t, 0, stats[2].Depth)
+	require.Equal(t, 2, stats[3].Depth)
+	require.GreaterOrEqual(t, stats[3].OldestWait, 10*time.Millisecond)
+
+	finish()
+
+	require.Zero(t, s.Stats()[3].Depth)
+	require.GreaterOrEqual(t, s.Stats()[3].AverageWait, 10*time.Millisecond)
+}
+
+func TestScheduler_Closed(t *testing.T) {
+	t.Parallel()
+
+	s := tester.NewScheduler[task](1, func(task) {}, classify)
+	s.Close()
+
+	require.Error(t, s.Do(context.Background(), task{}))
+}
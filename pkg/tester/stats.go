@@ -0,0 +1,45 @@
+package tester
+
+import (
+	"context"
+	"time"
+)
+
+// containerStats measures the runs in a container: the host cgroup is read directly
+// when it is visible, see stats_linux.go, the Docker stats API is used otherwise,
+// see stats_docker.go
+type containerStats interface {
+	// begin is called right before a run, the peak is measured from then on
+	begin(ctx context.Context) error
+	// usage reads the counters, the peak is the one since begin
+	usage(ctx context.Context) (cgroupUsage, error)
+	// reusable tells whether the next run in the container is measured right as well
+	reusable() bool
+	close() error
+}
+
+// cgroupUsage is the accounting of a cgroup, the counters only grow
+type cgroupUsage struct {
+	cpu      time.Duration // user and system time
+	oomKills int64
+	peak     int64 // bytes
+}
+
+// since returns the usage of a run that started at before,
+// the peak is measured by the caller and taken as is
+func (u cgroupUsage) since(before cgroupUsage) cgroupUsage {
+	return cgroupUsage{
+		cpu:      u.cpu - before.cpu,
+		oomKills: u.oomKills - before.oomKills,
+		peak:     u.peak,
+	}
+}
+
+func (u cgroupUsage) metrics(wall time.Duration, exitCode int64) *Metrics {
+	return &Metrics{
+		CPUTime:  u.cpu,
+		WallTime: wall,
+		Memory:   u.peak / 1024,
+		ExitCode: int(exitCode),
+	}
+}
@@ -0,0 +1,130 @@
+package tester
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"sync"
+	"time"
+)
+
+// statsInterval is how often the memory of a run is sampled through the Docker API,
+// the peaks shorter than that might be missed
+const statsInterval = 10 * time.Millisecond
+
+// dockerStats measures the runs with the stats API of Docker, for the hosts where
+// the cgroups of containers are not visible, e.g. the server runs in a container itself.
+// The API has no resettable memory peak, so the memory is sampled during the run
+// and the container is used only once.
+type dockerStats struct {
+	dockerClient *client.Client
+	id           string
+
+	mu   sync.Mutex
+	peak int64 // bytes
+
+	stop context.CancelFunc
+	done chan struct{}
+}
+
+func newDockerStats(dockerClient *client.Client, id string) *dockerStats {
+	return &dockerStats{
+		dockerClient: dockerClient,
+		id:           id,
+	}
+}
+
+// begin starts sampling the memory until the container is removed
+func (s *dockerStats) begin(ctx context.Context) error {
+	if _, err := s.sample(ctx); err != nil {
+		return err
+	}
+
+	if s.stop != nil {
+		return nil
+	}
+
+	ctx, s.stop = context.WithCancel(context.WithoutCancel(ctx))
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(statsInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// a failed sample is only a missed one, usage reports the errors
+				_, _ = s.sample(ctx)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// sample reads the stats and raises the peak
+func (s *dockerStats) sample(ctx context.Context) (*container.StatsResponse, error) {
+	resp, err := s.dockerClient.ContainerStatsOneShot(ctx, s.id)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var stats container.StatsResponse
+	err = json.NewDecoder(resp.Body).Decode(&stats)
+	if err != nil {
+		return nil, err
+	}
+
+	// max_usage is only reported by cgroup v1
+	s.mu.Lock()
+	s.peak = max(s.peak, int64(stats.MemoryStats.Usage), int64(stats.MemoryStats.MaxUsage))
+	s.mu.Unlock()
+
+	return &stats, nil
+}
+
+func (s *dockerStats) usage(ctx context.Context) (cgroupUsage, error) {
+	stats, err := s.sample(ctx)
+	if err != nil {
+		return cgroupUsage{}, err
+	}
+
+	inspect, err := s.dockerClient.ContainerInspect(ctx, s.id)
+	if err != nil {
+		return cgroupUsage{}, err
+	}
+
+	s.mu.Lock()
+	usage := cgroupUsage{
+		cpu:  time.Duration(stats.CPUStats.CPUUsage.TotalUsage),
+		peak: s.peak,
+	}
+	s.mu.Unlock()
+
+	// the oom kills are not counted by the API, reaching the limit is taken for one as well,
+	// there is no swap to go on with
+	limit := int64(stats.MemoryStats.Limit)
+	if inspect.State != nil && inspect.State.OOMKilled || limit > 0 && usage.peak >= limit {
+		usage.oomKills = 1
+	}
+
+	return usage, nil
+}
+
+func (s *dockerStats) reusable() bool {
+	return false
+}
+
+func (s *dockerStats) close() error {
+	if s.stop != nil {
+		s.stop()
+		<-s.done
+	}
+	return nil
+}
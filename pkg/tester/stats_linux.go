@@ -0,0 +1,90 @@
+package tester
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// containerCgroup reads the cgroup v2 accounting of a running container from the host,
+// it is not visible when the server itself runs in a container
+type containerCgroup struct {
+	dir string
+
+	// memory.peak is kept open, since linux 6.12 a write to it resets
+	// the peak seen through the same file descriptor
+	peak       *os.File
+	resettable bool
+}
+
+// openContainerCgroup finds the cgroup of the container by the host pid of its init process
+func openContainerCgroup(pid int, id string) (containerStats, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return nil, err
+	}
+
+	// the unified hierarchy is the only line with the hierarchy id 0
+	var path string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if p, ok := strings.CutPrefix(scanner.Text(), "0::"); ok {
+			path = p
+			break
+		}
+	}
+	if path == "" {
+		return nil, errors.New("container is not in a cgroup v2 hierarchy")
+	}
+	// the pid is the one of the host, in another pid namespace it is some other process
+	if !strings.Contains(path, id) {
+		return nil, errors.New("container cgroup is not visible")
+	}
+
+	c := &containerCgroup{dir: filepath.Join(cgroupRoot, path)}
+
+	c.peak, err = os.OpenFile(filepath.Join(c.dir, "memory.peak"), os.O_RDWR, 0)
+	if err != nil {
+		c.peak, err = os.Open(filepath.Join(c.dir, "memory.peak"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c.resettable = c.resetPeak() == nil
+
+	return c, nil
+}
+
+func (c *containerCgroup) resetPeak() error {
+	_, err := c.peak.WriteAt([]byte("0"), 0)
+	return err
+}
+
+func (c *containerCgroup) begin(context.Context) error {
+	if !c.resettable {
+		return nil
+	}
+	return c.resetPeak()
+}
+
+// usage reads the counters, the peak is the one since the last reset
+func (c *containerCgroup) usage(context.Context) (cgroupUsage, error) {
+	return readCgroupUsage(c.dir, c.peak)
+}
+
+// reusable tells whether the peak can be reset, only a fresh container measures memory right otherwise
+func (c *containerCgroup) reusable() bool {
+	return c.resettable
+}
+
+func (c *containerCgroup) close() error {
+	return c.peak.Close()
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package tester
+
+import (
+	"errors"
+)
+
+// openContainerCgroup is the fast path of linux hosts, see stats_linux.go,
+// the Docker stats API is used elsewhere
+func openContainerCgroup(pid int, id string) (containerStats, error) {
+	return nil, errors.New("container cgroups are only readable on linux")
+}
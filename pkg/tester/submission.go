@@ -0,0 +1,206 @@
+package tester
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// SubmissionArchiveLimits bounds the zips of multi-file solutions,
+// they are much smaller than the archives of problems
+var SubmissionArchiveLimits = ArchiveLimits{
+	MaxFiles:            256,
+	MaxTotalSize:        64 * 1024 * 1024, // 64 MB
+	MaxCompressionRatio: 1000,
+}
+
+// archiveDir is where the files of a multi-file solution are unpacked in the work dir
+const archiveDir = "src"
+
+// ArchiveSolution is implemented by the solutions which might be submitted
+// as a zip of files, Solution returns the zip then
+type ArchiveSolution interface {
+	Archive() bool
+}
+
+func isArchive(s Solution) bool {
+	a, ok := s.(ArchiveSolution)
+	return ok && a.Archive()
+}
+
+// GradedSolution is implemented by the solutions of the problems with a grader,
+// Grader returns the grader the source is compiled with, nil if there is none
+type GradedSolution interface {
+	Grader() []byte
+}
+
+func graderOf(s Solution) []byte {
+	g, ok := s.(GradedSolution)
+	if !ok {
+		return nil
+	}
+	return g.Grader()
+}
+
+// CheckGrader tells whether the language can link solutions with a grader
+func CheckGrader(lang models.LanguageName) error {
+	const op = "tester.CheckGrader"
+
+	l := GetConfig(lang)
+	if l == nil || l.Grader == nil {
+		return pkg.Wrap(pkg.ErrBadInput, nil, op, "language does not take graders")
+	}
+
+	return nil
+}
+
+// forSolution resolves the build of a single source, linked with the grader if the solution has one
+func (l *Language) forSolution(s Solution) (*Language, error) {
+	const op = "Language.forSolution"
+
+	resolved := l.forSource(s.Solution())
+	if graderOf(s) == nil {
+		return resolved, nil
+	}
+	if resolved.Grader == nil {
+		return nil, fmt.Errorf("language %d does not take graders", l.Id)
+	}
+	if resolved.SourceFile == resolved.Grader.File {
+		return nil, compilationError(op, fmt.Sprintf("%s is the file of the grader, rename the main class", resolved.SourceFile))
+	}
+	return resolved.forGrader(), nil
+}
+
+// prepareGrader writes the grader of the solution next to its source
+func prepareGrader(s Solution, lang *Language, workDir string) error {
+	grader := graderOf(s)
+	if grader == nil {
+		return nil
+	}
+	return os.WriteFile(filepath.Join(workDir, lang.Grader.File), grader, 0600)
+}
+
+func validArchivePath(name string) bool {
+	return name != "" && path.Clean(name) == name && !path.IsAbs(name) &&
+		name != ".." && !strings.HasPrefix(name, "../")
+}
+
+// CheckArchive tells whether the zip is a solution the language can build:
+// it has to be a safe archive with the entrypoint of the language at its root
+func CheckArchive(lang models.LanguageName, data []byte) error {
+	const op = "CheckArchive"
+
+	l := GetConfig(lang)
+	if l == nil {
+		return pkg.Wrap(pkg.ErrBadInput, nil, op, "unknown language")
+	}
+	if l.Archive == nil {
+		return pkg.Wrap(pkg.ErrBadInput, nil, op, fmt.Sprintf("%s solutions must be a single file", l.Name))
+	}
+
+	archive, err := NewArchiveReader(bytes.NewReader(data), int64(len(data)), SubmissionArchiveLimits)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range archive.Files() {
+		if entryName(file.Name) == l.Archive.Entrypoint {
+			return nil
+		}
+	}
+
+	return pkg.Wrap(pkg.ErrBadInput, nil, op, fmt.Sprintf("the archive has no %s", l.Archive.Entrypoint))
+}
+
+// prepareArchive unpacks a multi-file solution to the src dir of workDir
+func (t *Tester) prepareArchive(s Solution, workDir string) error {
+	data := s.Solution()
+
+	archive, err := NewArchiveReader(bytes.NewReader(data), int64(len(data)), SubmissionArchiveLimits)
+	if err != nil {
+		return err
+	}
+
+	return archive.Extract(filepath.Join(workDir, archiveDir), nil)
+}
+
+// outputsDir is where the answers to an output-only problem are unpacked in the work dir
+const outputsDir = "outputs"
+
+// outputExt is the extension of the answers to an output-only problem, e.g. 01.out for the test 01
+const outputExt = ".out"
+
+func isOutput(name string) bool {
+	return strings.HasSuffix(name, outputExt) && !strings.ContainsAny(name, `/\`)
+}
+
+// CheckOutputs tells whether the zip is a set of answers to an output-only problem,
+// the answers NN.out have to be at its root, the missing ones fail their tests
+func CheckOutputs(data []byte) error {
+	const op = "CheckOutputs"
+
+	archive, err := NewArchiveReader(bytes.NewReader(data), int64(len(data)), SubmissionArchiveLimits)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range archive.Files() {
+		if isOutput(file.Name) {
+			return nil
+		}
+	}
+
+	return pkg.Wrap(pkg.ErrBadInput, nil, op, fmt.Sprintf("the archive has no answers, e.g. 01%s", outputExt))
+}
+
+// prepareOutputs unpacks the answers to an output-only problem to the outputs dir of workDir
+func (t *Tester) prepareOutputs(s Solution, workDir string) error {
+	const op = "Tester.prepareOutputs"
+
+	if !isArchive(s) {
+		return pkg.Wrap(pkg.ErrBadInput, nil, op, "answers must be a zip")
+	}
+
+	data := s.Solution()
+
+	archive, err := NewArchiveReader(bytes.NewReader(data), int64(len(data)), SubmissionArchiveLimits)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(workDir, outputsDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return pkg.Wrap(pkg.ErrInternal, err, op, "failed to create outputs dir")
+	}
+
+	return archive.Extract(dir, isOutput)
+}
+
+// checkOutput checks the submitted answer to a test with the checker
+func (t *Tester) checkOutput(ctx context.Context, checker Checker, testsPath, workDir, testName string) (float64, error) {
+	const op = "Tester.checkOutput"
+
+	output := filepath.Join(workDir, outputsDir, testName+outputExt)
+
+	exists, err := pathExists(output)
+	if err != nil {
+		return 0, pkg.Wrap(pkg.ErrInternal, err, op, "failed to find output")
+	}
+	if !exists {
+		return 0, pkg.Wrap(WrongAnswerErr, nil, op, fmt.Sprintf("no %s%s in the archive", testName, outputExt))
+	}
+
+	input := filepath.Join(testsPath, "tests", testName)
+	score, err := checker(ctx, input, input+".a", output)
+	if err != nil {
+		return score, pkg.Wrap(nil, err, op, "failed to check output")
+	}
+
+	return 1, nil
+}
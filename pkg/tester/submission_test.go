@@ -0,0 +1,208 @@
+package tester_test
+
+import (
+	"context"
+	"errors"
+	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"github.com/Vyacheslav1557/tester/pkg/tester"
+	"github.com/stretchr/testify/require"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type outputsPacket struct {
+	zipPath string
+	meta    *models.Meta
+}
+
+func (p outputsPacket) ContestId() int32          { return 0 }
+func (p outputsPacket) UniquePacketName() string  { return "outputs" }
+func (p outputsPacket) ZipPath() string           { return p.zipPath }
+func (p outputsPacket) TL() int64                 { return 1000 }
+func (p outputsPacket) ML() int64                 { return 256 }
+func (p outputsPacket) Meta() *models.Meta        { return p.meta }
+func (p outputsPacket) Strategy() models.Strategy { return models.StrategyIOI }
+func (p outputsPacket) Priority() tester.Priority { return tester.PriorityPractice }
+func (p outputsPacket) Type() models.ProblemType  { return models.ProblemOutputOnly }
+
+type outputsSolution struct {
+	zip []byte
+}
+
+func (s outputsSolution) Id() int32                 { return 1 }
+func (s outputsSolution) UserId() int32             { return 1 }
+func (s outputsSolution) Solution() []byte          { return s.zip }
+func (s outputsSolution) Lang() models.LanguageName { return models.Cpp }
+func (s outputsSolution) Archive() bool             { return true }
+
+func TestCheckOutputs(t *testing.T) {
+	t.Parallel()
+
+	data := func(entries ...entry) []byte {
+		b, err := io.ReadAll(buildZip(t, entries...))
+		require.NoError(t, err)
+		return b
+	}
+
+	require.NoError(t, tester.CheckOutputs(data(entry{name: "01.out", body: []byte("1")})))
+	require.ErrorIs(t, tester.CheckOutputs(data(entry{name: "answers/01.out", body: []byte("1")})), pkg.ErrBadInput)
+	require.ErrorIs(t, tester.CheckOutputs([]byte("1")), pkg.ErrBadInput)
+}
+
+func TestTester_Test_OutputOnly(t *testing.T) {
+	t.Parallel()
+
+	cacheDir := t.TempDir()
+	zipPath := filepath.Join(cacheDir, "tests.zip")
+
+	tests, err := io.ReadAll(buildZip(t,
+		entry{name: "tests/01", body: []byte("1 2\n")},
+		entry{name: "tests/01.a", body: []byte("3\n")},
+		entry{name: "tests/02", body: []byte("2 2\n")},
+		entry{name: "tests/02.a", body: []byte("4\n")},
+		entry{name: "tests/03", body: []byte("0 0\n")},
+		entry{name: "tests/03.a", body: []byte("0\n")},
+	))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(zipPath, tests, 0644))
+
+	answers, err := io.ReadAll(buildZip(t,
+		entry{name: "01.out", body: []byte("3")},
+		entry{name: "02.out", body: []byte("5")},
+	))
+	require.NoError(t, err)
+
+	// nothing is compiled or executed, so there is no executor
+	tr := tester.NewTester(cacheDir, nil, 1, 1)
+
+	packet := outputsPacket{
+		zipPath: zipPath,
+		meta:    &models.Meta{Count: 3, Names: []string{"01", "02", "03"}},
+	}
+
+	states := make(map[int]models.State)
+	for msg := range tr.Test(context.Background(), packet, outputsSolution{zip: answers}) {
+		require.NotEqual(t, "Compiling", msg.Details)
+		if msg.Test != 0 {
+			states[msg.Test] = msg.State
+		}
+	}
+
+	require.Equal(t, map[int]models.State{
+		1: models.Accepted,
+		2: models.GotWA,
+		3: models.GotWA, // missing
+	}, states)
+}
+
+// sourceRecorder keeps the sources it is asked to compile and fails the build
+type sourceRecorder struct {
+	sources map[string]string
+}
+
+func (e *sourceRecorder) Compile(_ context.Context, lang *tester.Language, path string) error {
+	data, err := os.ReadFile(filepath.Join(path, lang.SourceFile))
+	if err != nil {
+		return err
+	}
+	e.sources[lang.SourceFile] = string(data)
+	return errors.New("not compiled")
+}
+
+func (e *sourceRecorder) Execute(context.Context, *tester.Language, tester.Limits, string, io.Reader) (*tester.Metrics, error) {
+	return nil, errors.New("not executed")
+}
+
+type sourceSolution struct {
+	source string
+	lang   models.LanguageName
+}
+
+func (s sourceSolution) Id() int32                 { return 1 }
+func (s sourceSolution) UserId() int32             { return 1 }
+func (s sourceSolution) Solution() []byte          { return []byte(s.source) }
+func (s sourceSolution) Lang() models.LanguageName { return s.lang }
+
+type programPacket struct {
+	outputsPacket
+}
+
+func (p programPacket) Type() models.ProblemType { return models.ProblemStandard }
+
+func TestTester_Test_JavaPackage(t *testing.T) {
+	t.Parallel()
+
+	cacheDir := t.TempDir()
+	zipPath := filepath.Join(cacheDir, "tests.zip")
+
+	tests, err := io.ReadAll(buildZip(t,
+		entry{name: "tests/01", body: []byte("1 2\n")},
+		entry{name: "tests/01.a", body: []byte("3\n")},
+	))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(zipPath, tests, 0644))
+
+	executor := &sourceRecorder{sources: make(map[string]string)}
+	tr := tester.NewTester(cacheDir, executor, 1, 1)
+
+	packet := programPacket{outputsPacket{
+		zipPath: zipPath,
+		meta:    &models.Meta{Count: 1, Names: []string{"01"}},
+	}}
+	solution := sourceSolution{
+		source: "package ru.contest.a;\n\npublic final class Solution {\n}\n",
+		lang:   models.Java,
+	}
+
+	for range tr.Test(context.Background(), packet, solution) {
+	}
+
+	require.Equal(t, map[string]string{
+		"Solution.java": "                     \n\npublic final class Solution {\n}\n",
+	}, executor.sources)
+}
+
+type gradedSolution struct {
+	sourceSolution
+	grader string
+}
+
+func (s gradedSolution) Grader() []byte { return []byte(s.grader) }
+
+func TestTester_Test_GraderClash(t *testing.T) {
+	t.Parallel()
+
+	cacheDir := t.TempDir()
+	zipPath := filepath.Join(cacheDir, "tests.zip")
+
+	tests, err := io.ReadAll(buildZip(t,
+		entry{name: "tests/01", body: []byte("1 2\n")},
+		entry{name: "tests/01.a", body: []byte("3\n")},
+	))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(zipPath, tests, 0644))
+
+	executor := &sourceRecorder{sources: make(map[string]string)}
+	tr := tester.NewTester(cacheDir, executor, 1, 1)
+
+	packet := programPacket{outputsPacket{
+		zipPath: zipPath,
+		meta:    &models.Meta{Count: 1, Names: []string{"01"}},
+	}}
+	solution := gradedSolution{
+		sourceSolution: sourceSolution{source: "public class Grader {\n}\n", lang: models.Java},
+		grader:         "public class Grader {\n}\n",
+	}
+
+	var last tester.TestingMessage
+	for msg := range tr.Test(context.Background(), packet, solution) {
+		last = msg
+	}
+
+	require.Equal(t, models.GotCE, last.State)
+	require.Contains(t, last.Stderr, "Grader.java")
+	require.Empty(t, executor.sources, "nothing is compiled")
+}
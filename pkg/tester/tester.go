@@ -10,28 +10,40 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 )
 
 type Tester struct {
-	pool     *Pool[ExecuteMessage]
+	pool     *Scheduler[ExecuteMessage]
+	runPool  *Pool[ExecuteMessage] // custom invocations, never compete with submissions
 	cacheDir string
 	compiler Compiler
+
+	checkersMu sync.Mutex // builds of the checkers, see testlib.go
 }
 
-func NewTester(cacheDir string, executor Executor, n int) *Tester {
+func NewTester(cacheDir string, executor Executor, n int, runN int) *Tester {
 	t := &Tester{
 		cacheDir: cacheDir,
 		compiler: executor,
 	}
 
-	t.pool = NewPool[ExecuteMessage](n, t.newExecutorWrapper(executor))
+	t.pool = NewScheduler[ExecuteMessage](n, t.newExecutorWrapper(executor), func(msg ExecuteMessage) (Priority, int32) {
+		return msg.class.priority, msg.class.user
+	})
+	t.runPool = NewPool[ExecuteMessage](runN, t.newExecutorWrapper(executor))
 
 	return t
 }
 
+// QueueStats returns the state of the submission queue per priority
+func (t *Tester) QueueStats() []QueueStats {
+	return t.pool.Stats()
+}
+
 type Compiler interface {
-	Compile(ctx context.Context, cfg Config, path string) error
+	Compile(ctx context.Context, lang *Language, path string) error
 }
 
 type Packet interface {
@@ -41,32 +53,54 @@ type Packet interface {
 	TL() int64
 	ML() int64
 	Meta() *models.Meta
+	Strategy() models.Strategy
+	Priority() Priority
+	Type() models.ProblemType
 }
 
 type Solution interface {
 	Id() int32
+	UserId() int32
 	Solution() []byte
 	Lang() models.LanguageName
 }
 
 type TestingMessage struct {
+	Test    int          // 1-based index of the test in Meta.Names, zero for other messages
+	State   models.State // verdict of the test or of the compilation, Accepted if the test has passed
+	Score   float64      // share of the points of the test given by the checker, 1 if the test has passed
 	Metrics *Metrics
 	Err     error
 	Details string
+	Stderr  string // of the compiler on CE or of the solution on a failed test, truncated
+}
+
+// schedulingClass tells the scheduler whose run it is and how urgent it is
+type schedulingClass struct {
+	priority Priority
+	user     int32
 }
 
 type ExecuteMessage struct {
 	callback func(msg TestingMessage)
 	ctx      context.Context
-	cfg      Config
+	class    schedulingClass
+	lang     *Language
+	limits   Limits
 	workDir  string
 	in       io.Reader
 }
 
 func (t *Tester) newExecutorWrapper(executor Executor) func(ExecuteMessage) {
 	return func(msg ExecuteMessage) {
-		err := executor.Execute(msg.ctx, msg.cfg, msg.workDir, msg.in)
-		msg.callback(TestingMessage{Err: err})
+		// the run might have been canceled while it was queued
+		if err := msg.ctx.Err(); err != nil {
+			msg.callback(TestingMessage{Err: err})
+			return
+		}
+
+		metrics, err := executor.Execute(msg.ctx, msg.lang, msg.limits, msg.workDir, msg.in)
+		msg.callback(TestingMessage{Metrics: metrics, Err: err})
 	}
 }
 
@@ -102,15 +136,25 @@ func (t *Tester) prepareTests(p Packet) (string, error) {
 	return testsPath, nil
 }
 
-func (t *Tester) prepareSource(s Solution, workDir string) (string, error) {
-	sourcePath := filepath.Join(workDir, "source")
+// RemoveTests drops the unpacked tests of the packet from the cache
+func (t *Tester) RemoveTests(p Packet) error {
+	return os.RemoveAll(filepath.Join(t.cacheDir, "tests", p.UniquePacketName()))
+}
+
+func (t *Tester) prepareSource(s Solution, lang *Language, workDir string) (string, error) {
+	sourcePath := filepath.Join(workDir, lang.SourceFile)
+
+	err := os.WriteFile(sourcePath, lang.stripPackage(s.Solution()), 0600)
+	if err != nil {
+		return "", err
+	}
 
-	file, err := os.OpenFile(sourcePath, os.O_CREATE, 0600)
+	err = writeDependencies(s, lang, workDir)
 	if err != nil {
 		return "", err
 	}
 
-	_, err = file.Write(s.Solution())
+	err = prepareGrader(s, lang, workDir)
 	if err != nil {
 		return "", err
 	}
@@ -143,43 +187,106 @@ func (t *Tester) prepareBuild(testDir, buildPath string) (string, error) {
 	return buildCopyPath, nil
 }
 
-func (t *Tester) test(p Packet, s Solution, buildPath, testsPath, testName string) (*Metrics, error) {
+// test runs one test, the solution's stderr and the score of the checker are returned along with a verdict
+func (t *Tester) test(
+	ctx context.Context,
+	p Packet,
+	class schedulingClass,
+	lang *Language,
+	checker Checker,
+	buildPath, testsPath, testName string,
+) (*Metrics, string, float64, error) {
 	const op = "Tester.test"
 
 	testDir, err := os.MkdirTemp("", "test")
 	if err != nil {
-		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to create test dir")
+		return nil, "", 0, pkg.Wrap(pkg.ErrInternal, err, op, "failed to create test dir")
 	}
+	defer os.RemoveAll(testDir)
 
 	_, err = t.prepareBuild(testDir, buildPath)
 	if err != nil {
-		return nil, err
+		return nil, "", 0, err
 	}
 
 	tests, err := os.OpenFile(testsPath, os.O_RDONLY, 0600)
 	if err != nil {
-		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to open tests")
+		return nil, "", 0, pkg.Wrap(pkg.ErrInternal, err, op, "failed to open tests")
 	}
 	defer tests.Close()
 
 	in, err := os.OpenFile(filepath.Join(testsPath, "tests", testName), os.O_RDONLY, 0600)
 	if err != nil {
-		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to open test")
+		return nil, "", 0, pkg.Wrap(pkg.ErrInternal, err, op, "failed to open test")
 	}
 	defer in.Close()
 
-	ch := make(chan TestingMessage)
-	defer close(ch)
+	metrics, err := t.execute(ctx, t.pool, class, lang, Limits{TL: p.TL(), ML: p.ML()}, testDir, in)
+	if err != nil {
+		var stateErr *StateErr
+		if !errors.As(err, &stateErr) {
+			return nil, "", 0, err
+		}
+
+		// verdicts of the sandbox come with whatever was measured before the kill,
+		// stderr has already been truncated by the executor
+		stderr, readErr := os.ReadFile(filepath.Join(testDir, "stderr.txt"))
+		if readErr != nil {
+			return nil, "", 0, pkg.Wrap(pkg.ErrInternal, readErr, op, "failed to read stderr")
+		}
+		return metrics, t.sanitize(string(stderr), testDir), 0, err
+	}
+
+	// RLIMIT_CPU only has a granularity of seconds
+	if metrics.CPUTime.Milliseconds() > lang.TL(p.TL()) {
+		return metrics, "", 0, pkg.Wrap(TimeLimitExceededErr, nil, op, "time limit exceeded")
+	}
 
-	ctx := context.TODO()
+	if metrics.Memory >= lang.ML(p.ML())*1024 {
+		return metrics, "", 0, pkg.Wrap(MemoryLimitExceededErr, nil, op, "memory limit exceeded")
+	}
+
+	input := filepath.Join(testsPath, "tests", testName)
+	expected := filepath.Join(testsPath, "tests", testName+".a")
+	actual := filepath.Join(testDir, "output.txt")
+
+	score, err := checker(ctx, input, expected, actual)
+	if err != nil {
+		return metrics, "", score, pkg.Wrap(nil, err, op, "failed to check output")
+	}
 
-	err = t.pool.Do(ctx, ExecuteMessage{
+	return metrics, "", 1, nil
+}
+
+// executeQueue is either the scheduler of the submissions or the pool of custom invocations
+type executeQueue interface {
+	Do(ctx context.Context, task ExecuteMessage) error
+}
+
+// execute runs the build in workDir on one of the queue workers and waits for it
+func (t *Tester) execute(
+	ctx context.Context,
+	queue executeQueue,
+	class schedulingClass,
+	lang *Language,
+	limits Limits,
+	workDir string,
+	in io.Reader,
+) (*Metrics, error) {
+	const op = "Tester.execute"
+
+	// the worker never blocks on a caller that has given up
+	ch := make(chan TestingMessage, 1)
+
+	err := queue.Do(ctx, ExecuteMessage{
 		callback: func(msg TestingMessage) {
 			ch <- msg
 		},
 		ctx:     ctx,
-		cfg:     GetConfig(s.Lang()),
-		workDir: testDir,
+		class:   class,
+		lang:    lang,
+		limits:  limits,
+		workDir: workDir,
 		in:      in,
 	})
 
@@ -189,132 +296,249 @@ func (t *Tester) test(p Packet, s Solution, buildPath, testsPath, testName strin
 
 	select {
 	case msg := <-ch:
-		if msg.Err != nil {
-			return nil, msg.Err
-		}
-		break
+		return msg.Metrics, msg.Err
 	case <-ctx.Done():
 		return nil, pkg.Wrap(pkg.ErrInternal, nil, op, "timeout")
 	}
-
-	metricsPath := filepath.Join(testDir, "time.txt")
-	metrics, err := parseMetrics(metricsPath)
-	if err != nil {
-		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to parse metrics")
-	}
-
-	if metrics.ElapsedTime.Milliseconds() > p.TL() {
-		return metrics, pkg.Wrap(TimeLimitExceededErr, nil, op, "time limit exceeded")
-	}
-
-	if int64(metrics.MaximumResidentSetSize) >= p.ML()*1024 {
-		return metrics, pkg.Wrap(MemoryLimitExceededErr, nil, op, "memory limit exceeded")
-	}
-
-	expected := filepath.Join(testsPath, "tests", testName+".a")
-	actual := filepath.Join(testDir, "output.txt")
-
-	err = compareFiles(expected, actual, 1e-6)
-	if err != nil {
-		return metrics, pkg.Wrap(nil, err, op, "failed to compare files")
-	}
-
-	return metrics, nil
 }
 
+// Test runs the solution on every test of the packet according to its strategy,
+// a verdict is sent for each test. The channel is closed once testing is over,
+// canceling ctx stops the runs left.
 func (t *Tester) Test(ctx context.Context, packet Packet, s Solution) <-chan TestingMessage {
 	const op = "Tester.Test"
 
+	class := schedulingClass{priority: packet.Priority(), user: s.UserId()}
+
 	ch := make(chan TestingMessage)
 	go func() {
 		defer close(ch)
 
-		ch <- TestingMessage{Details: "Preparing"}
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
 
-		testsPath, err := t.prepareTests(packet)
-		if err != nil {
-			ch <- TestingMessage{
-				Err: pkg.Wrap(pkg.ErrInternal, err, op, "failed to prepare tests"),
+		// the caller may stop reading, e.g. at the first failure
+		send := func(msg TestingMessage) bool {
+			select {
+			case ch <- msg:
+				return true
+			case <-ctx.Done():
+				return false
 			}
-			return
 		}
 
-		lang := GetConfig(s.Lang())
-		if lang == nil {
-			ch <- TestingMessage{
-				Err: pkg.Wrap(pkg.ErrInternal, nil, op, "unknown language"),
-			}
+		send(TestingMessage{Details: "Preparing"})
+
+		testsPath, err := t.prepareTests(packet)
+		if err != nil {
+			send(TestingMessage{
+				Err: pkg.Wrap(pkg.ErrInternal, err, op, "failed to prepare tests"),
+			})
 			return
 		}
 
 		workDir, err := os.MkdirTemp("", "tester")
 		if err != nil {
-			ch <- TestingMessage{
+			send(TestingMessage{
 				Err: pkg.Wrap(pkg.ErrInternal, err, op, "failed to create work dir"),
-			}
+			})
 			return
 		}
 		defer os.RemoveAll(workDir)
 
-		_, err = t.prepareSource(s, workDir)
-		if err != nil {
-			ch <- TestingMessage{
-				Err: pkg.Wrap(pkg.ErrInternal, err, op, "failed to prepare source"),
-			}
-			return
-		}
-
-		compileCmd := lang.CompileCMD()
-		if len(compileCmd) > 0 {
-			ch <- TestingMessage{Details: "Compiling"}
+		outputOnly := packet.Type() == models.ProblemOutputOnly
 
-			err = t.compiler.Compile(ctx, lang, workDir)
+		var lang *Language
+		if outputOnly {
+			// the answers are checked as they are, there is nothing to build and run
+			err = t.prepareOutputs(s, workDir)
 			if err != nil {
-				ch <- TestingMessage{
-					Err: pkg.Wrap(nil, err, op, "failed to compile"),
-				}
+				send(TestingMessage{
+					Err: pkg.Wrap(pkg.ErrInternal, err, op, "failed to prepare outputs"),
+				})
+				return
+			}
+		} else {
+			var ok bool
+			lang, ok = t.build(ctx, s, workDir, send)
+			if !ok {
 				return
 			}
 		}
 
+		checker, err := t.checkerFor(ctx, packet, class, testsPath)
+		if err != nil {
+			send(TestingMessage{
+				Err: pkg.Wrap(pkg.ErrInternal, err, op, "failed to prepare checker"),
+			})
+			return
+		}
+
 		buildPath := filepath.Join(workDir, "solution")
 
-		ch <- TestingMessage{Details: "Testing"}
+		send(TestingMessage{Details: "Testing"})
 
 		meta := packet.Meta()
 
-		wg := sync.WaitGroup{}
-		wg.Add(meta.Count)
-
-		for j := 0; j < meta.Count; j++ {
+		run := func(j int) TestingMessage {
 			testName := meta.Names[j]
-			ch <- TestingMessage{Details: fmt.Sprintf("Testing %s", testName)}
 
-			go func() {
-				defer wg.Done()
+			var (
+				metrics *Metrics
+				stderr  string
+				score   float64
+				err     error
+			)
+			if outputOnly {
+				score, err = t.checkOutput(ctx, checker, testsPath, workDir, testName)
+			} else {
+				metrics, stderr, score, err = t.test(ctx, packet, class, lang, checker, buildPath, testsPath, testName)
+			}
+			if err != nil {
+				msg := TestingMessage{
+					Test:    j + 1,
+					Metrics: metrics,
+					Err:     pkg.Wrap(pkg.ErrInternal, err, op, "failed to test"),
+					Stderr:  stderr,
+					Score:   score,
+				}
+
+				var stateErr *StateErr
+				if errors.As(err, &stateErr) {
+					msg.State = stateErr.State
+				}
+				return msg
+			}
 
-				metrics, err := t.test(packet, s, buildPath, testsPath, testName)
-				if err != nil {
-					ch <- TestingMessage{
-						Metrics: metrics,
-						Err:     pkg.Wrap(pkg.ErrInternal, err, op, "failed to test"),
-					}
+			return TestingMessage{
+				Test:    j + 1,
+				State:   models.Accepted,
+				Score:   1,
+				Metrics: metrics,
+				Details: fmt.Sprintf("%s passed", testName),
+			}
+		}
+
+		switch packet.Strategy() {
+		case models.StrategyIOI:
+			wg := sync.WaitGroup{}
+			wg.Add(meta.Count)
+
+			for j := 0; j < meta.Count; j++ {
+				send(TestingMessage{Details: fmt.Sprintf("Testing %s", meta.Names[j])})
+
+				go func() {
+					defer wg.Done()
+					send(run(j))
+				}()
+			}
+
+			wg.Wait()
+		default:
+			// ICPC, tests go one by one up to the first failure
+			for j := 0; j < meta.Count; j++ {
+				if !send(TestingMessage{Details: fmt.Sprintf("Testing %s", meta.Names[j])}) {
 					return
 				}
 
-				ch <- TestingMessage{
-					Metrics: metrics,
-					Details: fmt.Sprintf("%s passed", testName),
+				msg := run(j)
+				if !send(msg) || msg.Err != nil {
+					return
 				}
-			}()
+			}
 		}
-
-		wg.Wait()
 	}()
 
 	return ch
 }
 
+// build compiles the solution in workDir, the failures are sent as they are
+func (t *Tester) build(ctx context.Context, s Solution, workDir string, send func(TestingMessage) bool) (*Language, bool) {
+	const op = "Tester.build"
+
+	lang := GetConfig(s.Lang())
+	if lang == nil {
+		send(TestingMessage{
+			Err: pkg.Wrap(pkg.ErrInternal, nil, op, "unknown language"),
+		})
+		return nil, false
+	}
+
+	var err error
+	archive := isArchive(s)
+	if archive {
+		if lang.Archive == nil || graderOf(s) != nil {
+			send(TestingMessage{
+				Err: pkg.Wrap(pkg.ErrInternal, nil, op, "language does not take archives"),
+			})
+			return nil, false
+		}
+		lang = lang.forArchive()
+	} else {
+		lang, err = lang.forSolution(s)
+		if err != nil {
+			// a source the build cannot take is a compilation error of the author
+			var stateErr *StateErr
+			if errors.As(err, &stateErr) {
+				send(TestingMessage{
+					State:  stateErr.State,
+					Err:    pkg.Wrap(nil, err, op, "failed to resolve build"),
+					Stderr: CompileLog(err),
+				})
+				return nil, false
+			}
+
+			send(TestingMessage{
+				Err: pkg.Wrap(pkg.ErrInternal, err, op, "failed to resolve build"),
+			})
+			return nil, false
+		}
+	}
+
+	if archive {
+		err = t.prepareArchive(s, workDir)
+	} else {
+		_, err = t.prepareSource(s, lang, workDir)
+	}
+	if err != nil {
+		send(TestingMessage{
+			Err: pkg.Wrap(pkg.ErrInternal, err, op, "failed to prepare source"),
+		})
+		return nil, false
+	}
+
+	if len(lang.Compile) > 0 {
+		send(TestingMessage{Details: "Compiling"})
+
+		err = t.compiler.Compile(ctx, lang, workDir)
+		if err != nil {
+			msg := TestingMessage{
+				Err:    pkg.Wrap(nil, err, op, "failed to compile"),
+				Stderr: t.sanitize(CompileLog(err), workDir),
+			}
+
+			var stateErr *StateErr
+			if errors.As(err, &stateErr) {
+				msg.State = stateErr.State
+			}
+			send(msg)
+			return nil, false
+		}
+	}
+
+	return lang, true
+}
+
+// sanitize hides host paths in the output shown to users,
+// the work dir is /code inside the sandbox anyway
+func (t *Tester) sanitize(output, workDir string) string {
+	output = strings.ReplaceAll(output, workDir, "/code")
+	if t.cacheDir != "" {
+		output = strings.ReplaceAll(output, t.cacheDir, "<cache>")
+	}
+	return output
+}
+
 func pathExists(path string) (bool, error) {
 	_, err := os.Stat(path)
 	if err == nil {
@@ -0,0 +1,204 @@
+package tester
+
+import (
+	"context"
+	"errors"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CheckerDir is where the tests archive keeps the testlib checker of a problem,
+// its source along with the headers it includes
+const CheckerDir = "checker"
+
+const maxCheckerMessage = 1024 // 1 KB per test
+
+// checkers are trusted like validators, the limits only keep a broken one from hanging a worker
+var checkerLimits = Limits{TL: 10000, ML: 256}
+
+// exit codes of testlib checkers, the others are failures of the checker
+const (
+	testlibOK     = 0
+	testlibWA     = 1
+	testlibPE     = 2
+	testlibPoints = 7
+)
+
+// the files of a test are passed to the checker as `checker input output answer`,
+// output.txt of the work dir is taken by the stdout of the checker itself
+var checkerFiles = [3]string{"input.txt", "out.txt", "answer.txt"}
+
+// checkerFor returns the checker of the packet, the outputs are compared token
+// by token unless the problem has a testlib checker
+func (t *Tester) checkerFor(ctx context.Context, p Packet, class schedulingClass, testsPath string) (Checker, error) {
+	const op = "Tester.checkerFor"
+
+	jury := p.Meta().Checker
+	if jury == nil {
+		return checkTokens, nil
+	}
+
+	lang := GetConfig(jury.Language)
+	if lang == nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, nil, op, "unknown checker language")
+	}
+
+	lang, buildPath, err := t.buildChecker(ctx, lang, filepath.Join(testsPath, CheckerDir), jury.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, inputPath, answerPath, outputPath string) (float64, error) {
+		return t.runChecker(ctx, class, lang, buildPath, [3]string{inputPath, outputPath, answerPath})
+	}, nil
+}
+
+// buildChecker compiles the checker once for the unpacked tests, the build is kept next to its source
+func (t *Tester) buildChecker(ctx context.Context, lang *Language, dir, source string) (*Language, string, error) {
+	const op = "Tester.buildChecker"
+
+	data, err := os.ReadFile(filepath.Join(dir, source))
+	if err != nil {
+		return nil, "", pkg.Wrap(pkg.ErrInternal, err, op, "failed to read checker")
+	}
+	lang = lang.forSource(data)
+
+	t.checkersMu.Lock()
+	defer t.checkersMu.Unlock()
+
+	buildDir := filepath.Join(dir, "build")
+	buildPath := filepath.Join(buildDir, "solution")
+
+	exists, err := pathExists(buildPath)
+	if err != nil {
+		return nil, "", pkg.Wrap(pkg.ErrInternal, err, op, "failed to find checker build")
+	}
+	if exists {
+		return lang, buildPath, nil
+	}
+
+	workDir, err := os.MkdirTemp("", "checker")
+	if err != nil {
+		return nil, "", pkg.Wrap(pkg.ErrInternal, err, op, "failed to create work dir")
+	}
+	defer os.RemoveAll(workDir)
+
+	err = copyFiles(dir, workDir)
+	if err != nil {
+		return nil, "", pkg.Wrap(pkg.ErrInternal, err, op, "failed to copy checker")
+	}
+
+	err = os.Rename(filepath.Join(workDir, source), filepath.Join(workDir, lang.SourceFile))
+	if err != nil {
+		return nil, "", pkg.Wrap(pkg.ErrInternal, err, op, "failed to prepare checker source")
+	}
+
+	if len(lang.Compile) > 0 {
+		err = t.compiler.Compile(ctx, lang, workDir)
+		if err != nil {
+			return nil, "", pkg.Wrap(pkg.ErrInternal, err, op,
+				"failed to compile checker "+truncate(CompileLog(err), maxCheckerMessage))
+		}
+	}
+
+	err = os.MkdirAll(buildDir, 0755)
+	if err != nil {
+		return nil, "", pkg.Wrap(pkg.ErrInternal, err, op, "failed to create checker build dir")
+	}
+
+	_, err = t.prepareBuild(buildDir, filepath.Join(workDir, "solution"))
+	if err != nil {
+		os.RemoveAll(buildDir)
+		return nil, "", err
+	}
+
+	return lang, buildPath, nil
+}
+
+// runChecker runs the checker on a test and maps its exit code to the share of the points.
+// The points of a testlib checker, quitp(x), are taken as the share of the points of the test.
+func (t *Tester) runChecker(ctx context.Context, class schedulingClass, lang *Language, buildPath string, files [3]string) (float64, error) {
+	const op = "Tester.runChecker"
+
+	checkDir, err := os.MkdirTemp("", "check")
+	if err != nil {
+		return 0, pkg.Wrap(pkg.ErrInternal, err, op, "failed to create check dir")
+	}
+	defer os.RemoveAll(checkDir)
+
+	_, err = t.prepareBuild(checkDir, buildPath)
+	if err != nil {
+		return 0, err
+	}
+
+	run := *lang
+	run.Execute = slices.Clone(lang.Execute)
+	for i, name := range checkerFiles {
+		data, err := os.ReadFile(files[i])
+		if err != nil {
+			return 0, pkg.Wrap(pkg.ErrInternal, err, op, "failed to read "+name)
+		}
+
+		err = os.WriteFile(filepath.Join(checkDir, name), data, 0644)
+		if err != nil {
+			return 0, pkg.Wrap(pkg.ErrInternal, err, op, "failed to write "+name)
+		}
+
+		run.Execute = append(run.Execute, path.Join(codeDir, name))
+	}
+
+	metrics, err := t.execute(ctx, t.pool, class, &run, checkerLimits, checkDir, strings.NewReader(""))
+	if err != nil && !errors.Is(err, RuntimeErr) {
+		return 0, pkg.Wrap(pkg.ErrInternal, err, op, "failed to run checker")
+	}
+	if metrics == nil {
+		return 0, pkg.Wrap(pkg.ErrInternal, nil, op, "checker has not run")
+	}
+
+	stderr, err := os.ReadFile(filepath.Join(checkDir, "stderr.txt"))
+	if err != nil {
+		return 0, pkg.Wrap(pkg.ErrInternal, err, op, "failed to read checker output")
+	}
+	msg := truncate(strings.TrimSpace(string(stderr)), maxCheckerMessage)
+
+	switch metrics.ExitCode {
+	case testlibOK:
+		return 1, nil
+	case testlibWA:
+		return 0, pkg.Wrap(WrongAnswerErr, nil, op, msg)
+	case testlibPE:
+		return 0, pkg.Wrap(PresentationErr, nil, op, msg)
+	case testlibPoints:
+		share, ok := testlibShare(msg)
+		if !ok {
+			return 0, pkg.Wrap(pkg.ErrInternal, nil, op, "checker gave no points: "+msg)
+		}
+		if share == 1 {
+			return 1, nil
+		}
+		return share, pkg.Wrap(WrongAnswerErr, nil, op, msg)
+	default:
+		return 0, pkg.Wrap(pkg.ErrInternal, nil, op,
+			"checker failed with exit code "+strconv.Itoa(metrics.ExitCode)+": "+msg)
+	}
+}
+
+// testlibShare parses the "points 0.5 ..." message of quitp, clamped to the share from 0 to 1
+func testlibShare(msg string) (float64, bool) {
+	fields := strings.Fields(msg)
+	if len(fields) < 2 || fields[0] != "points" {
+		return 0, false
+	}
+
+	points, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return min(max(points, 0), 1), true
+}
@@ -0,0 +1,129 @@
+package tester_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/Vyacheslav1557/tester/internal/models"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"github.com/Vyacheslav1557/tester/pkg/tester"
+	"github.com/stretchr/testify/require"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testlibExecutor plays a testlib checker: the output has to be the answer,
+// "half" gets half of the points
+type testlibExecutor struct {
+	mu       sync.Mutex
+	compiled []string
+}
+
+func (e *testlibExecutor) Compile(_ context.Context, lang *tester.Language, path string) error {
+	for _, name := range []string{lang.SourceFile, "testlib.h"} {
+		if _, err := os.Stat(filepath.Join(path, name)); err != nil {
+			return err
+		}
+	}
+
+	e.mu.Lock()
+	e.compiled = append(e.compiled, lang.SourceFile)
+	e.mu.Unlock()
+
+	return os.WriteFile(filepath.Join(path, "solution"), []byte("checker"), 0755)
+}
+
+func (e *testlibExecutor) Execute(_ context.Context, lang *tester.Language, _ tester.Limits, path string, _ io.Reader) (*tester.Metrics, error) {
+	args := lang.Execute[len(lang.Execute)-3:]
+
+	read := func(arg string) string {
+		data, err := os.ReadFile(filepath.Join(path, filepath.Base(arg)))
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(data))
+	}
+	output, answer := read(args[1]), read(args[2])
+
+	code, msg := 0, "ok"
+	switch {
+	case output == "half":
+		code, msg = 7, "points 0.5 half of it"
+	case output != answer:
+		code, msg = 1, fmt.Sprintf("wrong answer expected %s, found %s", answer, output)
+	}
+
+	if err := os.WriteFile(filepath.Join(path, "stderr.txt"), []byte(msg), 0644); err != nil {
+		return nil, err
+	}
+	if code != 0 {
+		return &tester.Metrics{ExitCode: code}, pkg.Wrap(tester.RuntimeErr, nil, "testlibExecutor.Execute", "exit code")
+	}
+	return &tester.Metrics{}, nil
+}
+
+func TestTester_Test_Checker(t *testing.T) {
+	t.Parallel()
+
+	cacheDir := t.TempDir()
+	zipPath := filepath.Join(cacheDir, "tests.zip")
+
+	tests, err := io.ReadAll(buildZip(t,
+		entry{name: "tests/01", body: []byte("1 2\n")},
+		entry{name: "tests/01.a", body: []byte("3\n")},
+		entry{name: "tests/02", body: []byte("2 2\n")},
+		entry{name: "tests/02.a", body: []byte("4\n")},
+		entry{name: "tests/03", body: []byte("0 0\n")},
+		entry{name: "tests/03.a", body: []byte("0\n")},
+		entry{name: "tests/04", body: []byte("2 3\n")},
+		entry{name: "tests/04.a", body: []byte("5\n")},
+		entry{name: "checker/check.cpp", body: []byte("#include \"testlib.h\"\n")},
+		entry{name: "checker/testlib.h", body: []byte("// testlib\n")},
+	))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(zipPath, tests, 0644))
+
+	answers, err := io.ReadAll(buildZip(t,
+		entry{name: "01.out", body: []byte("3")},
+		entry{name: "02.out", body: []byte("half")},
+		entry{name: "04.out", body: []byte("6")},
+	))
+	require.NoError(t, err)
+
+	executor := &testlibExecutor{}
+	tr := tester.NewTester(cacheDir, executor, 1, 1)
+
+	packet := outputsPacket{
+		zipPath: zipPath,
+		meta: &models.Meta{
+			Count:   4,
+			Names:   []string{"01", "02", "03", "04"},
+			Checker: &models.Checker{Language: models.Cpp, Source: "check.cpp"},
+		},
+	}
+
+	// the checker is built once for the unpacked tests
+	for range 2 {
+		states := make(map[int]models.State)
+		scores := make(map[int]float64)
+		for msg := range tr.Test(context.Background(), packet, outputsSolution{zip: answers}) {
+			if msg.Test != 0 {
+				states[msg.Test] = msg.State
+				scores[msg.Test] = msg.Score
+			}
+		}
+
+		require.Equal(t, map[int]models.State{
+			1: models.Accepted,
+			2: models.GotWA, // partially correct
+			3: models.GotWA, // missing
+			4: models.GotWA,
+		}, states)
+		require.Equal(t, map[int]float64{1: 1, 2: 0.5, 3: 0, 4: 0}, scores)
+	}
+
+	require.Equal(t, []string{"source.cpp"}, executor.compiled)
+}
@@ -0,0 +1,176 @@
+package tester
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const maxValidatorMessage = 1024 // 1 KB per test
+
+// validators are trusted, they only get limits keeping a broken one from hanging a worker
+var validatorLimits = Limits{TL: 10000, ML: 256}
+
+// validators run while a problem is being prepared
+var validatorClass = schedulingClass{priority: PriorityPractice}
+
+// Dependencies is implemented by programs which need extra files
+// next to the source to compile, e.g. testlib.h for validators
+type Dependencies interface {
+	Dependencies() map[string][]byte
+}
+
+// ValidationFailure describes a test input rejected by the validator
+type ValidationFailure struct {
+	Test    string
+	Message string
+}
+
+// Validate compiles the validator and runs it over every test input of the packet.
+// A test is rejected when the validator exits with a non-zero status,
+// its stderr is reported as the reason.
+func (t *Tester) Validate(ctx context.Context, p Packet, validator Solution) ([]ValidationFailure, error) {
+	const op = "Tester.Validate"
+
+	lang := GetConfig(validator.Lang())
+	if lang == nil {
+		return nil, pkg.Wrap(pkg.ErrBadInput, nil, op, "unknown validator language")
+	}
+	lang = lang.forSource(validator.Solution())
+
+	testsPath, err := t.prepareTests(p)
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to prepare tests")
+	}
+
+	workDir, err := os.MkdirTemp("", "validator")
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to create work dir")
+	}
+	defer os.RemoveAll(workDir)
+
+	_, err = t.prepareSource(validator, lang, workDir)
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to prepare source")
+	}
+
+	if len(lang.Compile) > 0 {
+		err = t.compiler.Compile(ctx, lang, workDir)
+		if err != nil {
+			var cErr *pkg.CustomError
+			if errors.Is(err, CompilationErr) && errors.As(err, &cErr) {
+				return nil, pkg.Wrap(pkg.ErrBadInput, err, op,
+					"validator "+truncate(cErr.Message, maxValidatorMessage))
+			}
+			return nil, err
+		}
+	}
+
+	buildPath := filepath.Join(workDir, "solution")
+	meta := p.Meta()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		failures []ValidationFailure
+		errs     []error
+	)
+
+	wg.Add(meta.Count)
+	for _, testName := range meta.Names {
+		go func() {
+			defer wg.Done()
+
+			msg, err := t.validate(ctx, buildPath, testsPath, testName, lang)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			if msg != "" {
+				failures = append(failures, ValidationFailure{Test: testName, Message: msg})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(errs) != 0 {
+		return nil, pkg.Wrap(pkg.ErrInternal, errors.Join(errs...), op, "failed to run validator")
+	}
+
+	sort.Slice(failures, func(i, j int) bool {
+		return failures[i].Test < failures[j].Test
+	})
+
+	return failures, nil
+}
+
+// validate runs the validator on a single test and returns the rejection reason, if any
+func (t *Tester) validate(ctx context.Context, buildPath, testsPath, testName string, lang *Language) (string, error) {
+	const op = "Tester.validate"
+
+	testDir, err := os.MkdirTemp("", "validate")
+	if err != nil {
+		return "", pkg.Wrap(pkg.ErrInternal, err, op, "failed to create test dir")
+	}
+	defer os.RemoveAll(testDir)
+
+	_, err = t.prepareBuild(testDir, buildPath)
+	if err != nil {
+		return "", err
+	}
+
+	in, err := os.Open(filepath.Join(testsPath, "tests", testName))
+	if err != nil {
+		return "", pkg.Wrap(pkg.ErrInternal, err, op, "failed to open test")
+	}
+	defer in.Close()
+
+	_, err = t.execute(ctx, t.pool, validatorClass, lang, validatorLimits, testDir, in)
+	if err == nil {
+		return "", nil
+	}
+	if !errors.Is(err, RuntimeErr) {
+		return "", err
+	}
+
+	stderr, err := os.ReadFile(filepath.Join(testDir, "stderr.txt"))
+	if err != nil {
+		return "", pkg.Wrap(pkg.ErrInternal, err, op, "failed to read validator output")
+	}
+
+	msg := strings.TrimSpace(string(stderr))
+	if msg == "" {
+		msg = "validator exited with non-zero status"
+	}
+
+	return truncate(msg, maxValidatorMessage), nil
+}
+
+func writeDependencies(s Solution, lang *Language, workDir string) error {
+	deps, ok := s.(Dependencies)
+	if !ok {
+		return nil
+	}
+
+	for name, content := range deps.Dependencies() {
+		if name != filepath.Base(name) || name == lang.SourceFile || name == "solution" {
+			return fmt.Errorf("invalid dependency name %q", name)
+		}
+
+		if err := os.WriteFile(filepath.Join(workDir, name), content, 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
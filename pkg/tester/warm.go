@@ -0,0 +1,381 @@
+package tester
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Vyacheslav1557/tester/pkg"
+	"github.com/docker/docker/api/types/container"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultWarmMaxUses = 100
+
+// WarmPool makes the Docker executor keep pre-created containers of a language
+// and reuse them, otherwise every run gets a container of its own.
+// Containers are reused only where their memory peak can be reset, see stats_linux.go.
+type WarmPool struct {
+	Size    int `yaml:"size"`     // idle containers kept, zero disables the pool
+	MaxUses int `yaml:"max_uses"` // a container is recycled after this many runs
+}
+
+// runsDir is where the directory of a warm container is mounted,
+// every run gets a fresh subdirectory of it as its work dir
+const runsDir = "/runs"
+
+// execContainer is a running container the runs are executed in with exec.
+// A warm one has a host directory mounted at /runs, which is emptied after every run,
+// the container of a single run has the work dir mounted at /code instead.
+type execContainer struct {
+	id    string
+	dir   string // of a warm container only
+	uses  int
+	stats containerStats
+}
+
+// pool returns the pool of the language, ok is false if every run of it gets a container of its own
+func (e *DockerExecutor) pool(lang *Language) (p chan *execContainer, ok bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	p, ok = e.pools[lang.Id]
+	return p, ok
+}
+
+func (e *DockerExecutor) newPool(lang *Language) chan *execContainer {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	p := make(chan *execContainer, lang.WarmPool.Size)
+	e.pools[lang.Id] = p
+	return p
+}
+
+// Pooled tells whether the runs of the language are executed in warm containers
+func (e *DockerExecutor) Pooled(lang *Language) bool {
+	_, ok := e.pool(lang)
+	return ok
+}
+
+// Warm fills the pools of the given languages, the ones without a pool are skipped.
+// No language gets a pool if the memory peak of containers cannot be reset,
+// a container would be used only once anyway, and one created per run is cheaper.
+func (e *DockerExecutor) Warm(ctx context.Context, languages []*Language) error {
+	for _, lang := range languages {
+		for lang.WarmPool.Size > 0 {
+			p, ok := e.pool(lang)
+			if ok && len(p) == lang.WarmPool.Size {
+				break
+			}
+
+			c, err := e.createWarm(ctx, lang)
+			if err != nil {
+				return err
+			}
+
+			if !c.stats.reusable() {
+				return e.removeContainer(ctx, c)
+			}
+
+			if !ok {
+				p = e.newPool(lang)
+			}
+			p <- c
+		}
+	}
+	return nil
+}
+
+// Close removes the idle containers of all pools
+func (e *DockerExecutor) Close(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var errs []error
+	for _, p := range e.pools {
+		for len(p) > 0 {
+			errs = append(errs, e.removeContainer(ctx, <-p))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// createWarm creates a container for the pool of the language, with a directory of its own at /runs
+func (e *DockerExecutor) createWarm(ctx context.Context, lang *Language) (*execContainer, error) {
+	const op = "DockerExecutor.createWarm"
+
+	err := os.MkdirAll(e.warmDir, 0755)
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to create warm dir")
+	}
+
+	dir, err := os.MkdirTemp(e.warmDir, strconv.Itoa(int(lang.Id))+"-")
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to create container dir")
+	}
+
+	c, err := e.createContainer(ctx, lang, fmt.Sprintf("%s:%s:ro", dir, runsDir), lang.ML(defaultML)*1024*1024)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	c.dir = dir
+
+	return c, nil
+}
+
+// createContainer starts a container of the language with the given bind mount
+// and memory limit in bytes, it idles until it is removed
+func (e *DockerExecutor) createContainer(ctx context.Context, lang *Language, bind string, memory int64) (*execContainer, error) {
+	const op = "DockerExecutor.createContainer"
+
+	var pidsLimit int64 = 100
+	resp, err := e.dockerClient.ContainerCreate(ctx,
+		&container.Config{
+			Image:           lang.Image,
+			Cmd:             []string{"sleep", "infinity"},
+			NetworkDisabled: true,
+			// runs are executed as 1000:1000, so they can kill each other's leftovers but not the container
+			User: "0:0",
+		},
+		&container.HostConfig{
+			Binds: []string{
+				bind,
+			},
+			CapDrop: []string{
+				"ALL",
+			},
+			Resources: container.Resources{
+				Memory:     memory,
+				MemorySwap: memory,
+				CPUPeriod:  100000,
+				CPUQuota:   100000,
+				PidsLimit:  &pidsLimit,
+			},
+			SecurityOpt: []string{
+				"apparmor:docker-default",
+			},
+			ReadonlyRootfs: true,
+		},
+		nil,
+		nil,
+		"",
+	)
+	if err != nil {
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to create container")
+	}
+
+	c := &execContainer{id: resp.ID}
+
+	err = e.dockerClient.ContainerStart(ctx, c.id, container.StartOptions{})
+	if err != nil {
+		err = errors.Join(err, e.removeContainer(ctx, c))
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to start container")
+	}
+
+	inspect, err := e.dockerClient.ContainerInspect(ctx, c.id)
+	if err != nil {
+		err = errors.Join(err, e.removeContainer(ctx, c))
+		return nil, pkg.Wrap(pkg.ErrInternal, err, op, "failed to inspect container")
+	}
+
+	// reading the cgroup from the host is faster and exact, when it is not
+	// visible the runs are measured through the API
+	c.stats, err = openContainerCgroup(inspect.State.Pid, c.id)
+	if err != nil {
+		c.stats = newDockerStats(e.dockerClient, c.id)
+	}
+
+	return c, nil
+}
+
+func (e *DockerExecutor) removeContainer(ctx context.Context, c *execContainer) error {
+	var errs []error
+	if c.stats != nil {
+		errs = append(errs, c.stats.close())
+	}
+	errs = append(errs, e.dockerClient.ContainerRemove(ctx, c.id, container.RemoveOptions{Force: true}))
+	if c.dir != "" {
+		errs = append(errs, os.RemoveAll(c.dir))
+	}
+	return errors.Join(errs...)
+}
+
+// kill stops a run by killing the whole container, the stats are read right before
+func (e *DockerExecutor) kill(ctx context.Context, c *execContainer) (cgroupUsage, error) {
+	usage, err := c.stats.usage(ctx)
+	if err != nil {
+		return usage, err
+	}
+	return usage, e.dockerClient.ContainerKill(ctx, c.id, "SIGKILL")
+}
+
+func (e *DockerExecutor) acquire(ctx context.Context, lang *Language, p chan *execContainer) (*execContainer, error) {
+	select {
+	case c := <-p:
+		return c, nil
+	default:
+		return e.createWarm(ctx, lang)
+	}
+}
+
+// release cleans the container up and puts it back into the pool,
+// broken, worn out and extra containers are removed instead, as well as
+// the ones whose stats would not measure the next run right.
+func (e *DockerExecutor) release(ctx context.Context, lang *Language, p chan *execContainer, c *execContainer, healthy bool) {
+	c.uses++
+
+	reusable := healthy && c.stats.reusable() && c.uses < lang.WarmPool.MaxUses
+	if reusable && e.cleanWarm(ctx, c) == nil {
+		select {
+		case p <- c:
+			return
+		default:
+		}
+	}
+
+	// the pool refills on demand, there is nobody to report the error to
+	_ = e.removeContainer(ctx, c)
+}
+
+// newRun creates a fresh work dir for a run in the directory of the container,
+// the path inside the container is returned as well
+func (c *execContainer) newRun() (string, string, error) {
+	dir, err := os.MkdirTemp(c.dir, "run-")
+	if err != nil {
+		return "", "", err
+	}
+	return dir, path.Join(runsDir, filepath.Base(dir)), nil
+}
+
+// cleanWarm kills whatever the last run has left behind and removes its work dir
+func (e *DockerExecutor) cleanWarm(ctx context.Context, c *execContainer) error {
+	exec, err := e.dockerClient.ContainerExecCreate(ctx, c.id, container.ExecOptions{
+		User: "1000:1000",
+		Cmd:  []string{"bash", "-c", "kill -9 -1 2>/dev/null; true"},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = e.dockerClient.ContainerExecStart(ctx, exec.ID, container.ExecStartOptions{Detach: true})
+	if err != nil {
+		return err
+	}
+
+	_, err = e.execExitCode(ctx, exec.ID)
+	if err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(c.dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// executeCMD applies the CPU limit per run, the container ulimits are fixed at creation.
+// Only the soft limit is set, a solution ignoring SIGXCPU runs into the wall time limit.
+// The execute commands refer to the work dir as /code, it is workDir here.
+func executeCMD(lang *Language, limits Limits, workDir string) []string {
+	cmd := []string{"bash", "-c", fmt.Sprintf(`ulimit -S -t %d && exec "$@"`, lang.CPULimit(limits)), "bash"}
+	for _, arg := range lang.ExecuteCMD(limits) {
+		cmd = append(cmd, relocate(arg, workDir))
+	}
+	return cmd
+}
+
+// codeDir is the work dir in the commands of languages
+const codeDir = "/code"
+
+// relocate replaces the work dir wherever an argument refers to it,
+// e.g. /code/solution, -Dpath=/code or cd /code in a shell command
+func relocate(arg, workDir string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(arg, codeDir)
+		if i < 0 {
+			break
+		}
+		end := i + len(codeDir)
+
+		// /code has to be a whole path of its own, not a part of /codes or /src/code
+		if (i == 0 || !isPathChar(arg[i-1])) && (end == len(arg) || arg[end] == '/' || !isPathChar(arg[end])) {
+			b.WriteString(arg[:i])
+			b.WriteString(workDir)
+		} else {
+			b.WriteString(arg[:end])
+		}
+		arg = arg[end:]
+	}
+	b.WriteString(arg)
+	return b.String()
+}
+
+// isPathChar tells whether c might be a part of a path next to /code
+func isPathChar(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '_' || c == '-' || c == '.' || c == '/'
+}
+
+// execExitCode waits for the exec to be reported as finished, its output is already closed
+func (e *DockerExecutor) execExitCode(ctx context.Context, execID string) (int64, error) {
+	for i := 0; ; i++ {
+		inspect, err := e.dockerClient.ContainerExecInspect(ctx, execID)
+		if err != nil {
+			return 0, err
+		}
+
+		if !inspect.Running {
+			return int64(inspect.ExitCode), nil
+		}
+
+		if i == 100 {
+			return 0, errors.New("exec is still running")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// copyFiles copies the regular files of src into dst
+func copyFiles(src, dst string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		data, err := os.ReadFile(filepath.Join(src, entry.Name()))
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(filepath.Join(dst, entry.Name()), data, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package tester_test
+
+import (
+	"github.com/Vyacheslav1557/tester/pkg/tester"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRelocate(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		arg       string
+		relocated string
+	}{
+		{arg: "/code", relocated: "/runs/run-1"},
+		{arg: "/code/solution", relocated: "/runs/run-1/solution"},
+		{arg: "-Dpath=/code/data", relocated: "-Dpath=/runs/run-1/data"},
+		{arg: "cd /code && ./solution < /code/input", relocated: "cd /runs/run-1 && ./solution < /runs/run-1/input"},
+		{arg: "'/code/{a}.java'", relocated: "'/runs/run-1/{a}.java'"},
+		{arg: "/codes/solution", relocated: "/codes/solution"},
+		{arg: "/src/code", relocated: "/src/code"},
+		{arg: "./solution", relocated: "./solution"},
+	} {
+		require.Equal(t, tc.relocated, tester.Relocate(tc.arg, "/runs/run-1"), tc.arg)
+	}
+}